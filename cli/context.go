@@ -30,3 +30,13 @@ func (self Arguments) Bool(key string) bool {
 func (self Arguments) StringSlice(key string) []string {
 	return self[key].([]string)
 }
+
+// Returns true if the string flag was given, also when its value is empty
+func (self Arguments) IsSet(key string) bool {
+	_, ok := self[isSetKey(key)]
+	return ok
+}
+
+func isSetKey(key string) string {
+	return key + ".isSet"
+}
@@ -124,7 +124,7 @@ func (self StringFlagParser) Capture(values []string) ([]string, map[string]inte
 		return remaining, map[string]interface{}{self.key: self.defaultValue}
 	}
 
-	return remaining, map[string]interface{}{self.key: value}
+	return remaining, map[string]interface{}{self.key: value, isSetKey(self.key): true}
 }
 
 func (self StringFlagParser) String() string {
@@ -72,3 +72,52 @@ func (self CachedMd5Comparer) cacheAdd(lf *drive.LocalFile, md5 string) {
 func (self CachedMd5Comparer) persist() {
 	writeJson(self.path, self.cache)
 }
+
+type ExportedDocInfo struct {
+	Id         string `json:"id"`
+	Version    int64  `json:"version"`
+	Modified   int64  `json:"modified"`
+	ExportMime string `json:"exportMime"`
+}
+
+func NewCachedExportTracker(path string) CachedExportTracker {
+	cache := map[string]*ExportedDocInfo{}
+
+	f, err := os.Open(path)
+	if err == nil {
+		json.NewDecoder(f).Decode(&cache)
+	}
+	f.Close()
+	return CachedExportTracker{path, cache}
+}
+
+type CachedExportTracker struct {
+	path  string
+	cache map[string]*ExportedDocInfo
+}
+
+func (self CachedExportTracker) Changed(local *drive.LocalFile, remote *drive.RemoteFile) bool {
+	cached, found := self.cache[local.AbsPath()]
+	if !found {
+		return true
+	}
+
+	return cached.Id != remote.Id() ||
+		cached.Version != remote.Version() ||
+		cached.Modified != remote.Modified().UnixNano() ||
+		cached.ExportMime != remote.ExportMime()
+}
+
+func (self CachedExportTracker) Exported(absPath string, remote *drive.RemoteFile) {
+	self.cache[absPath] = &ExportedDocInfo{
+		Id:         remote.Id(),
+		Version:    remote.Version(),
+		Modified:   remote.Modified().UnixNano(),
+		ExportMime: remote.ExportMime(),
+	}
+	self.persist()
+}
+
+func (self CachedExportTracker) persist() {
+	writeJson(self.path, self.cache)
+}
@@ -0,0 +1,242 @@
+package drive
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+type AuditSharingArgs struct {
+	Out            io.Writer
+	Status         io.Writer
+	Id             string
+	AllowedDomains []string
+	Format         string
+	Fix            bool
+}
+
+func (args *AuditSharingArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+// A permission in the audit report
+type auditEntry struct {
+	Path         string `json:"path"`
+	FileId       string `json:"fileId"`
+	PermissionId string `json:"permissionId"`
+	Type         string `json:"type"`
+	Role         string `json:"role"`
+	Principal    string `json:"principal,omitempty"`
+	Expires      string `json:"expires,omitempty"`
+	Inherited    bool   `json:"inherited"`
+	Risk         string `json:"risk,omitempty"`
+}
+
+// Reports the permissions of all files in the directory tree, grants to
+// anyone and to users, groups and domains outside the allowed domains are
+// flagged. With Fix the flagged permissions are revoked, inherited
+// permissions are skipped as they must be revoked where they are granted
+func (self *Drive) AuditSharing(args AuditSharingArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(args.Format)
+	if format != "csv" && format != "json" {
+		return fmt.Errorf("Unknown report format '%s', valid formats are: csv, json", args.Format)
+	}
+
+	fmt.Fprintln(args.Status, "Listing files...")
+	tree, err := self.fileTree([]string{args.Id}, "id,name,mimeType")
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Status, "Listing permissions of %d files...\n", len(tree))
+
+	var ids []string
+	for _, tf := range tree {
+		ids = append(ids, tf.file.Id)
+	}
+
+	permissions, errors := self.batchListPermissions(ids)
+
+	// Map of file id -> parent id and file id -> permission ids,
+	// used to find permissions inherited outside of shared drives
+	parents := map[string]string{}
+	permissionIds := map[string]map[string]bool{}
+	for i, tf := range tree {
+		if errors[i] != nil {
+			return fmt.Errorf("Failed to list permissions of '%s': %s", tf.path, errors[i])
+		}
+
+		if tf.parent != nil {
+			parents[tf.file.Id] = tf.parent.file.Id
+		}
+		permissionIds[tf.file.Id] = map[string]bool{}
+		for _, p := range permissions[i] {
+			permissionIds[tf.file.Id][p.Id] = true
+		}
+	}
+
+	var entries []*auditEntry
+	for i, tf := range tree {
+		for _, p := range permissions[i] {
+			if p.Role == "owner" {
+				continue
+			}
+
+			entries = append(entries, &auditEntry{
+				Path:         tf.path,
+				FileId:       tf.file.Id,
+				PermissionId: p.Id,
+				Type:         p.Type,
+				Role:         p.Role,
+				Principal:    permissionPrincipal(p),
+				Expires:      p.ExpirationTime,
+				Inherited:    p.inherited() || grantedByAncestor(tf.file.Id, p.Id, parents, permissionIds),
+				Risk:         sharingRisk(p, args.AllowedDomains),
+			})
+		}
+	}
+
+	if format == "json" {
+		err = writeAuditJson(args.Out, entries)
+	} else {
+		err = writeAuditCsv(args.Out, entries)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write report: %s", err)
+	}
+
+	var flagged []*auditEntry
+	for _, e := range entries {
+		if e.Risk != "" {
+			flagged = append(flagged, e)
+		}
+	}
+
+	fmt.Fprintf(args.Status, "%d permissions on %d files, %d flagged\n", len(entries), len(tree), len(flagged))
+
+	if !args.Fix {
+		return nil
+	}
+
+	var failed int
+	for _, e := range flagged {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		if e.Inherited {
+			fmt.Fprintf(args.Status, "Skipping inherited %s permission on '%s'\n", e.Type, e.Path)
+			continue
+		}
+
+		err := self.RevokePermission(RevokePermissionArgs{
+			Out:          ioutil.Discard,
+			FileId:       e.FileId,
+			PermissionId: e.PermissionId,
+		})
+		if err != nil {
+			fmt.Fprintf(args.Status, "%s on '%s'\n", err, e.Path)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(args.Status, "Revoked %s permission %s on '%s'\n", e.Type, e.PermissionId, e.Path)
+	}
+
+	return batchError("revoke permissions of", failed, len(flagged))
+}
+
+func permissionPrincipal(p *permission) string {
+	if p.EmailAddress != "" {
+		return p.EmailAddress
+	}
+	return p.Domain
+}
+
+// Returns why the permission is risky, empty if it is not
+func sharingRisk(p *permission, allowedDomains []string) string {
+	switch p.Type {
+	case "anyone":
+		if p.AllowFileDiscovery {
+			return "public on the web"
+		}
+		return "anyone with the link"
+
+	case "domain":
+		if len(allowedDomains) > 0 && !isAllowedDomain(p.Domain, allowedDomains) {
+			return "external domain"
+		}
+
+	case "user", "group":
+		if len(allowedDomains) > 0 && !isAllowedDomain(emailDomain(p.EmailAddress), allowedDomains) {
+			return "external " + p.Type
+		}
+	}
+
+	return ""
+}
+
+// Subdomains of allowed domains are allowed as well
+func isAllowedDomain(domain string, allowedDomains []string) bool {
+	domain = strings.ToLower(domain)
+	for _, allowed := range allowedDomains {
+		allowed = strings.ToLower(allowed)
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func emailDomain(email string) string {
+	pos := strings.LastIndex(email, "@")
+	if pos == -1 {
+		return ""
+	}
+	return email[pos+1:]
+}
+
+func writeAuditCsv(out io.Writer, entries []*auditEntry) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"Path", "FileId", "PermissionId", "Type", "Role", "Principal", "Expires", "Inherited", "Risk"})
+
+	for _, e := range entries {
+		w.Write([]string{
+			e.Path,
+			e.FileId,
+			e.PermissionId,
+			e.Type,
+			e.Role,
+			e.Principal,
+			e.Expires,
+			formatBool(e.Inherited),
+			e.Risk,
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func writeAuditJson(out io.Writer, entries []*auditEntry) error {
+	if entries == nil {
+		entries = []*auditEntry{}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
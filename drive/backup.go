@@ -0,0 +1,537 @@
+package drive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const BackupManifestVersion = 1
+const BackupChunksDirName = "chunks"
+const BackupSnapshotsDirName = "snapshots"
+
+// Snapshot ids are the creation time in utc
+const backupSnapshotIdFormat = "20060102T150405Z"
+
+type backupManifest struct {
+	Version int           `json:"version"`
+	Id      string        `json:"id"`
+	Created string        `json:"created"`
+	Host    string        `json:"host"`
+	Path    string        `json:"path"`
+	Files   []*backupFile `json:"files"`
+}
+
+type backupFile struct {
+	Path     string      `json:"path"` // Relative to the backup root, slash separated
+	IsDir    bool        `json:"isDir,omitempty"`
+	Mode     os.FileMode `json:"mode"`
+	Size     int64       `json:"size,omitempty"`
+	Modified string      `json:"modified"`
+	Chunks   []string    `json:"chunks,omitempty"` // Sha256 of the content chunks in order
+}
+
+func (self *backupManifest) fileCount() int {
+	var count int
+	for _, f := range self.Files {
+		if !f.IsDir {
+			count++
+		}
+	}
+	return count
+}
+
+func (self *backupManifest) size() int64 {
+	var size int64
+	for _, f := range self.Files {
+		size += f.Size
+	}
+	return size
+}
+
+// A backup repository is a drive directory with a directory of content
+// addressed chunks and a directory with one manifest file per snapshot
+type backupRepo struct {
+	id          string
+	chunksId    string
+	snapshotsId string
+	// Sha256 -> file id of all stored chunks
+	chunks map[string]string
+}
+
+// A snapshot manifest file as stored in the repository
+type backupSnapshot struct {
+	fileId  string
+	id      string
+	created time.Time
+	host    string
+	files   int64
+	size    int64
+}
+
+func (self *Drive) openBackupRepo(id string, create bool) (*backupRepo, error) {
+	repo := &backupRepo{id: id, chunks: map[string]string{}}
+
+	dirs, err := self.listAllFiles(listAllFilesArgs{
+		query:  fmt.Sprintf("trashed = false and '%s' in parents and mimeType = '%s'", id, DirectoryMimeType),
+		fields: []googleapi.Field{"nextPageToken", "files(id,name)"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	for _, d := range dirs {
+		switch d.Name {
+		case BackupChunksDirName:
+			repo.chunksId = d.Id
+		case BackupSnapshotsDirName:
+			repo.snapshotsId = d.Id
+		}
+	}
+
+	if repo.chunksId == "" || repo.snapshotsId == "" {
+		if !create {
+			return nil, fmt.Errorf("Directory %s is not a backup repository", id)
+		}
+		if err := self.initBackupRepo(repo); err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+
+	chunks, err := self.listAllFiles(listAllFilesArgs{
+		query:  fmt.Sprintf("trashed = false and '%s' in parents", repo.chunksId),
+		fields: []googleapi.Field{"nextPageToken", "files(id,name)"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing chunks: %s", err)
+	}
+
+	for _, f := range chunks {
+		repo.chunks[f.Name] = f.Id
+	}
+
+	return repo, nil
+}
+
+func (self *Drive) initBackupRepo(repo *backupRepo) error {
+	if repo.chunksId == "" {
+		f, err := self.mkdir(MkdirArgs{Name: BackupChunksDirName, Parents: []string{repo.id}})
+		if err != nil {
+			return err
+		}
+		repo.chunksId = f.Id
+	}
+
+	if repo.snapshotsId == "" {
+		f, err := self.mkdir(MkdirArgs{Name: BackupSnapshotsDirName, Parents: []string{repo.id}})
+		if err != nil {
+			return err
+		}
+		repo.snapshotsId = f.Id
+	}
+
+	return nil
+}
+
+// Returns the snapshots of the repository, oldest first
+func (self *Drive) listBackupSnapshots(repo *backupRepo) ([]*backupSnapshot, error) {
+	files, err := self.listAllFiles(listAllFilesArgs{
+		query:     fmt.Sprintf("trashed = false and '%s' in parents", repo.snapshotsId),
+		fields:    []googleapi.Field{"nextPageToken", "files(id,name,appProperties)"},
+		sortOrder: "name",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed listing snapshots: %s", err)
+	}
+
+	var snapshots []*backupSnapshot
+	for _, f := range files {
+		id := f.Name[:len(f.Name)-len(filepath.Ext(f.Name))]
+		created, err := time.Parse(backupSnapshotIdFormat, id)
+		if err != nil {
+			// Not a manifest
+			continue
+		}
+
+		count, _ := strconv.ParseInt(f.AppProperties["files"], 10, 64)
+		size, _ := strconv.ParseInt(f.AppProperties["size"], 10, 64)
+
+		snapshots = append(snapshots, &backupSnapshot{
+			fileId:  f.Id,
+			id:      id,
+			created: created,
+			host:    f.AppProperties["host"],
+			files:   count,
+			size:    size,
+		})
+	}
+
+	return snapshots, nil
+}
+
+func (self *Drive) readBackupManifest(snapshot *backupSnapshot) (*backupManifest, error) {
+	content, err := self.downloadContent(snapshot.fileId)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &backupManifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("Failed to read manifest of snapshot %s: %s", snapshot.id, err)
+	}
+
+	if manifest.Version != BackupManifestVersion {
+		return nil, fmt.Errorf("Snapshot %s has unsupported version %d", snapshot.id, manifest.Version)
+	}
+
+	return manifest, nil
+}
+
+func (self *Drive) writeBackupManifest(repo *backupRepo, manifest *backupManifest) error {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	dstFile := &drive.File{
+		Name:     manifest.Id + ".json",
+		MimeType: "application/json",
+		Parents:  []string{repo.snapshotsId},
+		// Allows listing snapshots without downloading the manifests
+		AppProperties: map[string]string{
+			"host":  manifest.Host,
+			"files": strconv.Itoa(manifest.fileCount()),
+			"size":  strconv.FormatInt(manifest.size(), 10),
+		},
+	}
+
+	return self.retry(func(ctx context.Context) error {
+		_, err := self.service.Files.Create(dstFile).Fields("id").Context(ctx).Media(bytes.NewReader(content)).Do()
+		if err != nil && !isRetryableError(err) {
+			return fmt.Errorf("Failed to upload manifest: %s", err)
+		}
+		return err
+	})
+}
+
+func (self *Drive) downloadContent(id string) ([]byte, error) {
+	res, err := self.service.Files.Get(id).Context(self.ctx).Download()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to download file: %s", err)
+	}
+	return content, nil
+}
+
+type BackupArgs struct {
+	Out       io.Writer
+	Progress  io.Writer
+	Path      string
+	Id        string
+	ChunkSize int64
+	Timeout   time.Duration
+}
+
+func (args *BackupArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+type backupStats struct {
+	files         int
+	unchanged     int
+	chunks        int
+	storedChunks  int
+	bytes         int64
+	uploadedBytes int64
+}
+
+// Creates a new snapshot of the given local directory. Files are split into
+// content defined chunks and only chunks that are not in the repository are
+// uploaded. Files that did not change since the previous snapshot are not read
+func (self *Drive) Backup(args BackupArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	rootPath, err := filepath.Abs(args.Path)
+	if err != nil {
+		return fmt.Errorf("Failed to find absolute path: %s", err)
+	}
+
+	info, err := os.Stat(rootPath)
+	if err != nil {
+		return fmt.Errorf("Failed to stat path: %s", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", args.Path)
+	}
+
+	repo, err := self.openBackupRepo(args.Id, true)
+	if err != nil {
+		return err
+	}
+
+	previous, err := self.previousBackupFiles(repo, rootPath)
+	if err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	now := time.Now().UTC()
+
+	manifest := &backupManifest{
+		Version: BackupManifestVersion,
+		Id:      now.Format(backupSnapshotIdFormat),
+		Created: now.Format(time.RFC3339),
+		Host:    host,
+		Path:    rootPath,
+	}
+
+	stats := &backupStats{}
+	started := time.Now()
+
+	err = filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		// Only regular files and directories are backed up
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(rootPath, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		f := &backupFile{
+			Path:     filepath.ToSlash(relPath),
+			IsDir:    info.IsDir(),
+			Mode:     info.Mode().Perm(),
+			Modified: info.ModTime().UTC().Format(time.RFC3339Nano),
+		}
+
+		if !f.IsDir {
+			f.Size = info.Size()
+			if err := self.backupFile(repo, f, path, previous[f.Path], stats, args); err != nil {
+				return err
+			}
+		}
+
+		manifest.Files = append(manifest.Files, f)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := self.writeBackupManifest(repo, manifest); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Created snapshot %s of %d files (%d unchanged), %s\n", manifest.Id, stats.files, stats.unchanged, formatSize(stats.bytes, false))
+	fmt.Fprintf(args.Out, "Uploaded %d of %d chunks, %s in %s\n", stats.storedChunks, stats.chunks, formatSize(stats.uploadedBytes, false), time.Since(started).Truncate(time.Second))
+	return nil
+}
+
+// Returns the files of the latest snapshot of the same path, keyed by path
+func (self *Drive) previousBackupFiles(repo *backupRepo, rootPath string) (map[string]*backupFile, error) {
+	files := map[string]*backupFile{}
+
+	snapshots, err := self.listBackupSnapshots(repo)
+	if err != nil || len(snapshots) == 0 {
+		return files, err
+	}
+
+	manifest, err := self.readBackupManifest(snapshots[len(snapshots)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Path != rootPath {
+		return files, nil
+	}
+
+	for _, f := range manifest.Files {
+		files[f.Path] = f
+	}
+	return files, nil
+}
+
+func (self *Drive) backupFile(repo *backupRepo, f *backupFile, path string, previous *backupFile, stats *backupStats, args BackupArgs) error {
+	stats.files++
+	stats.bytes += f.Size
+
+	// Reuse the chunks of unchanged files if they are all still stored
+	if previous != nil && !previous.IsDir && previous.Size == f.Size && previous.Modified == f.Modified && repo.hasChunks(previous.Chunks) {
+		f.Chunks = previous.Chunks
+		stats.unchanged++
+		return nil
+	}
+
+	srcFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open file: %s", err)
+	}
+
+	// Close file on function exit
+	defer srcFile.Close()
+
+	fmt.Fprintf(args.Out, "Backing up %s\n", f.Path)
+
+	chunker := newChunker(getProgressReader(srcFile, args.Progress, f.Size))
+	for {
+		chunk, err := chunker.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read file: %s", err)
+		}
+
+		hash := fmt.Sprintf("%x", sha256.Sum256(chunk))
+		f.Chunks = append(f.Chunks, hash)
+		stats.chunks++
+
+		if _, found := repo.chunks[hash]; found {
+			continue
+		}
+
+		id, err := self.uploadChunk(repo, hash, chunk, args)
+		if err != nil {
+			return err
+		}
+
+		repo.chunks[hash] = id
+		stats.storedChunks++
+		stats.uploadedBytes += int64(len(chunk))
+	}
+
+	return nil
+}
+
+func (self *Drive) uploadChunk(repo *backupRepo, hash string, chunk []byte, args BackupArgs) (string, error) {
+	dstFile := &drive.File{
+		Name:     hash,
+		MimeType: "application/octet-stream",
+		Parents:  []string{repo.chunksId},
+	}
+
+	// Chunk size option
+	chunkSize := googleapi.ChunkSize(int(args.ChunkSize))
+
+	var id string
+	err := self.retry(func(ctx context.Context) error {
+		// Wrap reader in timeout reader
+		reader, ctx := getTimeoutReaderContext(ctx, bytes.NewReader(chunk), args.Timeout)
+
+		f, err := self.service.Files.Create(dstFile).Fields("id").Context(ctx).Media(reader, chunkSize).Do()
+		if err != nil {
+			if isRetryableError(err) {
+				return err
+			} else if self.isTimeoutError(err) {
+				return fmt.Errorf("Failed to upload chunk: timeout, no data was transferred for %v", args.Timeout)
+			}
+			return fmt.Errorf("Failed to upload chunk: %s", err)
+		}
+
+		id = f.Id
+		return nil
+	})
+	if err != nil && isRetryableError(err) {
+		return "", fmt.Errorf("Failed to upload chunk: %s", err)
+	}
+	return id, err
+}
+
+func (self *backupRepo) hasChunks(hashes []string) bool {
+	for _, hash := range hashes {
+		if _, found := self.chunks[hash]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+type ListBackupsArgs struct {
+	Out         io.Writer
+	Id          string
+	SkipHeader  bool
+	SizeInBytes bool
+}
+
+func (args *ListBackupsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+func (self *Drive) ListBackups(args ListBackupsArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	repo, err := self.openBackupRepo(args.Id, false)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := self.listBackupSnapshots(repo)
+	if err != nil {
+		return err
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Snapshot\tCreated\tHost\tFiles\tSize")
+	}
+
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n",
+			s.id,
+			s.created.Local().Format("2006-01-02 15:04:05"),
+			s.host,
+			s.files,
+			formatSize(s.size, args.SizeInBytes),
+		)
+	}
+
+	w.Flush()
+	return nil
+}
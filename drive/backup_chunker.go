@@ -0,0 +1,82 @@
+package drive
+
+import (
+	"bufio"
+	"io"
+)
+
+// Chunk size limits of the content defined chunking, a chunk boundary
+// is found on average every BackupAvgChunkSize bytes after the minimum
+const BackupMinChunkSize = 512 * 1024
+const BackupAvgChunkSize = 1024 * 1024
+const BackupMaxChunkSize = 4 * 1024 * 1024
+
+// Boundary mask with log2(BackupAvgChunkSize) bits set. The high bits
+// are used as they depend on the last 64 bytes, the low bits only
+// depend on the last few bytes
+const backupChunkMask = uint64(BackupAvgChunkSize-1) << 44
+
+// Random values used by the rolling hash, one per byte value
+var gearTable = newGearTable()
+
+// Splits a stream into content defined chunks using a gear rolling hash.
+// Boundaries depend on the content only, so inserting data in a file
+// only changes the chunks around the insertion
+type chunker struct {
+	reader *bufio.Reader
+	buf    []byte
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{
+		reader: bufio.NewReaderSize(r, 64*1024),
+		buf:    make([]byte, 0, BackupMaxChunkSize),
+	}
+}
+
+// Returns the next chunk, the returned slice is only valid until
+// the next call. io.EOF is returned when there are no more chunks
+func (self *chunker) next() ([]byte, error) {
+	self.buf = self.buf[:0]
+	var hash uint64
+
+	for len(self.buf) < BackupMaxChunkSize {
+		b, err := self.reader.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		self.buf = append(self.buf, b)
+		hash = (hash << 1) + gearTable[b]
+
+		if len(self.buf) >= BackupMinChunkSize && hash&backupChunkMask == 0 {
+			break
+		}
+	}
+
+	if len(self.buf) == 0 {
+		return nil, io.EOF
+	}
+
+	return self.buf, nil
+}
+
+// The table must never change, as that would change all chunk boundaries
+func newGearTable() [256]uint64 {
+	var table [256]uint64
+
+	// splitmix64 with a fixed seed
+	state := uint64(0x6764726976650001)
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+
+	return table
+}
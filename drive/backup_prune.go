@@ -0,0 +1,155 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+)
+
+type PruneBackupsArgs struct {
+	Out        io.Writer
+	Id         string
+	KeepDaily  int64
+	KeepWeekly int64
+	DryRun     bool
+}
+
+func (args *PruneBackupsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+// Removes the snapshots that are not kept by the retention policy
+// and the chunks that are no longer used by any snapshot
+func (self *Drive) PruneBackups(args PruneBackupsArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	if args.KeepDaily <= 0 && args.KeepWeekly <= 0 {
+		return fmt.Errorf("--keep-daily or --keep-weekly is required")
+	}
+
+	repo, err := self.openBackupRepo(args.Id, false)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := self.listBackupSnapshots(repo)
+	if err != nil {
+		return err
+	}
+
+	keep := backupsToKeep(snapshots, int(args.KeepDaily), int(args.KeepWeekly))
+
+	var removed []*backupSnapshot
+	for _, s := range snapshots {
+		if !keep[s.id] {
+			removed = append(removed, s)
+			fmt.Fprintf(args.Out, "Removing snapshot %s\n", s.id)
+		}
+	}
+
+	// Find the chunks still used by the kept snapshots
+	used := map[string]bool{}
+	for _, s := range snapshots {
+		if !keep[s.id] {
+			continue
+		}
+
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		manifest, err := self.readBackupManifest(s)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range manifest.Files {
+			for _, hash := range f.Chunks {
+				used[hash] = true
+			}
+		}
+	}
+
+	var unused []string
+	for hash, id := range repo.chunks {
+		if !used[hash] {
+			unused = append(unused, id)
+		}
+	}
+
+	if args.DryRun {
+		fmt.Fprintf(args.Out, "Would remove %d snapshots and %d unused chunks\n", len(removed), len(unused))
+		return nil
+	}
+
+	// Remove the manifests first, a snapshot must never refer to a removed chunk
+	var ids []string
+	for _, s := range removed {
+		ids = append(ids, s.fileId)
+	}
+
+	failed := self.deleteBackupFiles(ids)
+	if err := batchError("remove", failed, len(ids)); err != nil {
+		return err
+	}
+
+	failed = self.deleteBackupFiles(unused)
+
+	fmt.Fprintf(args.Out, "Removed %d snapshots and %d unused chunks\n", len(removed), len(unused)-failed)
+	return batchError("remove", failed, len(unused))
+}
+
+func (self *Drive) deleteBackupFiles(ids []string) int {
+	var requests []*batchRequest
+	for _, id := range ids {
+		requests = append(requests, &batchRequest{
+			method: "DELETE",
+			path:   "files/" + url.PathEscape(id),
+		})
+	}
+
+	var failed int
+	for _, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Returns the ids of the snapshots to keep, the newest snapshot of
+// each of the last daily days and weekly weeks with snapshots is kept
+func backupsToKeep(snapshots []*backupSnapshot, daily, weekly int) map[string]bool {
+	keep := map[string]bool{}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+
+	// Newest first
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		s := snapshots[i]
+		created := s.created.Local()
+
+		day := created.Format("2006-01-02")
+		if !days[day] && len(days) < daily {
+			days[day] = true
+			keep[s.id] = true
+		}
+
+		year, w := created.ISOWeek()
+		week := fmt.Sprintf("%d-%02d", year, w)
+		if !weeks[week] && len(weeks) < weekly {
+			weeks[week] = true
+			keep[s.id] = true
+		}
+	}
+
+	return keep
+}
@@ -0,0 +1,180 @@
+package drive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type RestoreBackupArgs struct {
+	Out        io.Writer
+	Progress   io.Writer
+	Id         string
+	SnapshotId string
+	Path       string
+	Force      bool
+}
+
+func (args *RestoreBackupArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+// Restores the files of a snapshot to the given local directory,
+// use 'latest' as snapshot id to restore the newest snapshot
+func (self *Drive) RestoreBackup(args RestoreBackupArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	repo, err := self.openBackupRepo(args.Id, false)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := self.listBackupSnapshots(repo)
+	if err != nil {
+		return err
+	}
+
+	snapshot := findBackupSnapshot(snapshots, args.SnapshotId)
+	if snapshot == nil {
+		return fmt.Errorf("Snapshot %s not found", args.SnapshotId)
+	}
+
+	manifest, err := self.readBackupManifest(snapshot)
+	if err != nil {
+		return err
+	}
+
+	var dirs []*backupFile
+	var restored int64
+
+	for _, f := range manifest.Files {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		fpath := filepath.Join(args.Path, filepath.FromSlash(f.Path))
+
+		if f.IsDir {
+			if err := os.MkdirAll(fpath, 0775); err != nil {
+				return fmt.Errorf("Failed to create directory: %s", err)
+			}
+			dirs = append(dirs, f)
+			continue
+		}
+
+		fmt.Fprintf(args.Out, "Restoring %s\n", fpath)
+
+		_, _, err := self.saveFile(saveFileArgs{
+			out:           args.Out,
+			body:          &backupChunkReader{drive: self, repo: repo, hashes: f.Chunks},
+			contentLength: f.Size,
+			fpath:         fpath,
+			force:         args.Force,
+			progress:      args.Progress,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := restoreFileInfo(fpath, f); err != nil {
+			return err
+		}
+		restored++
+	}
+
+	// Set directory times last, as restoring the files changes them
+	for i := len(dirs) - 1; i >= 0; i-- {
+		fpath := filepath.Join(args.Path, filepath.FromSlash(dirs[i].Path))
+		if err := restoreFileInfo(fpath, dirs[i]); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(args.Out, "Restored %d files from snapshot %s\n", restored, snapshot.id)
+	return nil
+}
+
+func findBackupSnapshot(snapshots []*backupSnapshot, id string) *backupSnapshot {
+	if id == "latest" && len(snapshots) > 0 {
+		return snapshots[len(snapshots)-1]
+	}
+
+	for _, s := range snapshots {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func restoreFileInfo(path string, f *backupFile) error {
+	if err := os.Chmod(path, f.Mode); err != nil {
+		return fmt.Errorf("Failed to set file mode: %s", err)
+	}
+
+	modified, err := time.Parse(time.RFC3339Nano, f.Modified)
+	if err != nil {
+		return nil
+	}
+
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		return fmt.Errorf("Failed to set modified time: %s", err)
+	}
+	return nil
+}
+
+// Reads the content of a file by downloading its chunks one at a time
+type backupChunkReader struct {
+	drive   *Drive
+	repo    *backupRepo
+	hashes  []string
+	current *bytes.Reader
+}
+
+func (self *backupChunkReader) Read(p []byte) (int, error) {
+	for self.current == nil || self.current.Len() == 0 {
+		if len(self.hashes) == 0 {
+			return 0, io.EOF
+		}
+
+		content, err := self.drive.readBackupChunk(self.repo, self.hashes[0])
+		if err != nil {
+			return 0, err
+		}
+
+		self.hashes = self.hashes[1:]
+		self.current = bytes.NewReader(content)
+	}
+
+	return self.current.Read(p)
+}
+
+func (self *Drive) readBackupChunk(repo *backupRepo, hash string) ([]byte, error) {
+	id, found := repo.chunks[hash]
+	if !found {
+		return nil, fmt.Errorf("Chunk %s is missing from the repository", hash)
+	}
+
+	content, err := self.downloadContent(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if fmt.Sprintf("%x", sha256.Sum256(content)) != hash {
+		return nil, fmt.Errorf("Chunk %s is corrupt", hash)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,377 @@
+package drive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const BatchEndpoint = "https://www.googleapis.com/batch/drive/v3"
+const BatchPathPrefix = "/drive/v3/"
+const ApiEndpoint = "https://www.googleapis.com" + BatchPathPrefix
+
+// Max number of calls in a single batch request
+const MaxBatchSize = 100
+
+// A single api call in a batch request
+type batchRequest struct {
+	method string
+	path   string // Relative to BatchPathPrefix, i.e. files/<id>
+	query  url.Values
+	body   interface{} // Sent as json if not nil
+}
+
+// See isRetryableRequest
+func (self *batchRequest) retryable() bool {
+	return isRetryableRequest(self.method, self.path, self.query)
+}
+
+type batchResult struct {
+	body []byte
+	err  error
+}
+
+func (self *batchResult) decode(v interface{}) error {
+	if self.err != nil {
+		return self.err
+	}
+	return json.Unmarshal(self.body, v)
+}
+
+// Executes the given requests using the batch endpoint, MaxBatchSize calls at
+// a time. The results are returned in the same order as the requests. Each
+// result holds its own error, sub-requests that failed with a backend or
+// rate limit error are retried unless they create files, the successful
+// ones are not sent again
+func (self *Drive) executeBatch(requests []*batchRequest) []*batchResult {
+	results := make([]*batchResult, len(requests))
+
+	var pending []int
+	for i := range requests {
+		pending = append(pending, i)
+	}
+
+	for try := 0; ; try++ {
+		var failed []int
+		var lastErr error
+
+		for i := 0; i < len(pending); i += MaxBatchSize {
+			chunk := pending[i:min(i+MaxBatchSize, len(pending))]
+
+			chunkResults, err := self.sendBatch(requests, chunk)
+			for j, idx := range chunk {
+				if err != nil {
+					results[idx] = &batchResult{err: err}
+				} else {
+					results[idx] = chunkResults[j]
+				}
+
+				if isRetryableError(results[idx].err) && requests[idx].retryable() {
+					failed = append(failed, idx)
+					lastErr = results[idx].err
+				}
+			}
+		}
+
+		if len(failed) == 0 || try >= self.retries.maxRetries || self.interrupted() {
+			break
+		}
+
+		select {
+		case <-time.After(retryDelay(lastErr, try)):
+		case <-self.ctx.Done():
+		}
+		pending = failed
+	}
+
+	return results
+}
+
+func (self *Drive) sendBatch(requests []*batchRequest, indexes []int) ([]*batchResult, error) {
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+
+	for j, idx := range indexes {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", "application/http")
+		header.Set("Content-ID", fmt.Sprintf("<item-%d>", j))
+
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writeBatchRequest(part, requests[idx]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", BatchEndpoint, buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "multipart/mixed; boundary="+w.Boundary())
+
+	// The transport must not resend batches with creations, see isRetryableRequest
+	for _, idx := range indexes {
+		if !requests[idx].retryable() {
+			req = req.WithContext(withCallerRetry(self.ctx))
+			break
+		}
+	}
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer googleapi.CloseBody(res)
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return nil, err
+	}
+
+	return readBatchResponse(res, len(indexes))
+}
+
+// Executes a single request without the batch endpoint, used
+// for api features that the vendored client does not support
+func (self *Drive) executeRequest(request *batchRequest) *batchResult {
+	target := ApiEndpoint + request.path
+	if len(request.query) > 0 {
+		target += "?" + request.query.Encode()
+	}
+
+	var body io.Reader
+	if request.body != nil {
+		data, err := json.Marshal(request.body)
+		if err != nil {
+			return &batchResult{err: err}
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(request.method, target, body)
+	if err != nil {
+		return &batchResult{err: err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	}
+
+	res, err := self.client.Do(req)
+	if err != nil {
+		return &batchResult{err: err}
+	}
+	defer googleapi.CloseBody(res)
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return &batchResult{err: err}
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	return &batchResult{body: data, err: err}
+}
+
+func writeBatchRequest(w io.Writer, req *batchRequest) error {
+	target := BatchPathPrefix + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	fmt.Fprintf(w, "%s %s HTTP/1.1\r\n", req.method, target)
+
+	if req.body == nil {
+		_, err := io.WriteString(w, "\r\n")
+		return err
+	}
+
+	body, err := json.Marshal(req.body)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Content-Type: application/json; charset=UTF-8\r\n")
+	fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body))
+	_, err = w.Write(body)
+	return err
+}
+
+func readBatchResponse(res *http.Response, count int) ([]*batchResult, error) {
+	_, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid batch response: %s", err)
+	}
+
+	results := make([]*batchResult, count)
+	reader := multipart.NewReader(res.Body, params["boundary"])
+
+	for i := 0; ; i++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid batch response: %s", err)
+		}
+
+		// Responses are expected to be in order, but use the content id when available
+		idx := i
+		if n, ok := batchResponseIndex(part.Header.Get("Content-ID")); ok {
+			idx = n
+		}
+		if idx < 0 || idx >= count {
+			return nil, fmt.Errorf("Invalid batch response: unexpected part %d", idx)
+		}
+
+		results[idx] = readBatchPart(part)
+	}
+
+	for i, result := range results {
+		if result == nil {
+			results[i] = &batchResult{err: fmt.Errorf("Missing response in batch")}
+		}
+	}
+
+	return results, nil
+}
+
+func readBatchPart(part io.Reader) *batchResult {
+	res, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return &batchResult{err: fmt.Errorf("Invalid batch response: %s", err)}
+	}
+	defer res.Body.Close()
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return &batchResult{err: err}
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return &batchResult{err: err}
+	}
+
+	return &batchResult{body: body}
+}
+
+// Parses content ids on the form <response-item-N>
+func batchResponseIndex(contentId string) (int, bool) {
+	s := strings.Trim(contentId, "<>")
+	pos := strings.LastIndex(s, "-")
+	if pos == -1 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(s[pos+1:])
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func fieldsQuery(fields ...googleapi.Field) url.Values {
+	return url.Values{"fields": {googleapi.CombineFields(fields)}}
+}
+
+// Gets the given files using batch requests, the returned
+// slices have the same order and length as the given ids
+func (self *Drive) batchGetFiles(ids []string, fields ...googleapi.Field) ([]*drive.File, []error) {
+	var requests []*batchRequest
+	for _, id := range ids {
+		requests = append(requests, &batchRequest{
+			method: "GET",
+			path:   "files/" + url.PathEscape(id),
+			query:  fieldsQuery(fields...),
+		})
+	}
+
+	files := make([]*drive.File, len(ids))
+	errors := make([]error, len(ids))
+
+	for i, result := range self.executeBatch(requests) {
+		f := &drive.File{}
+		if err := result.decode(f); err != nil {
+			errors[i] = err
+			continue
+		}
+		files[i] = f
+	}
+
+	return files, errors
+}
+
+// Lists the files matching each of the given queries. Every round sends one
+// batch with the next page of all queries that still have more pages
+func (self *Drive) batchListFiles(queries []string, fields ...googleapi.Field) ([][]*drive.File, error) {
+	files := make([][]*drive.File, len(queries))
+	pageTokens := make([]string, len(queries))
+
+	var pending []int
+	for i := range queries {
+		pending = append(pending, i)
+	}
+
+	for len(pending) > 0 {
+		var requests []*batchRequest
+		for _, idx := range pending {
+			query := fieldsQuery(fields...)
+			query.Set("q", queries[idx])
+			query.Set("pageSize", "1000")
+			if pageTokens[idx] != "" {
+				query.Set("pageToken", pageTokens[idx])
+			}
+
+			requests = append(requests, &batchRequest{
+				method: "GET",
+				path:   "files",
+				query:  query,
+			})
+		}
+
+		var next []int
+		for j, result := range self.executeBatch(requests) {
+			idx := pending[j]
+
+			fl := &drive.FileList{}
+			if err := result.decode(fl); err != nil {
+				return nil, err
+			}
+
+			files[idx] = append(files[idx], fl.Files...)
+			if fl.NextPageToken != "" {
+				pageTokens[idx] = fl.NextPageToken
+				next = append(next, idx)
+			}
+		}
+		pending = next
+	}
+
+	return files, nil
+}
+
+// Returns an error summarizing the failed items of a bulk operation, nil if none failed
+func batchError(action string, failed, total int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("Failed to %s %d of %d files", action, failed, total)
+}
@@ -0,0 +1,435 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const MetadataCacheVersion = 1
+
+var cacheChangeFields = []googleapi.Field{"nextPageToken", "newStartPageToken", "changes(fileId,removed,file(id,name,parents,mimeType,md5Checksum,size,createdTime,trashed))"}
+
+type cachedFile struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Parents     []string `json:"parents,omitempty"`
+	MimeType    string   `json:"mimeType"`
+	Md5Checksum string   `json:"md5,omitempty"`
+	Size        int64    `json:"size,omitempty"`
+	CreatedTime string   `json:"createdTime,omitempty"`
+}
+
+type metadataCacheData struct {
+	Version   int                    `json:"version"`
+	RootId    string                 `json:"rootId"`
+	PageToken string                 `json:"pageToken"`
+	Refreshed string                 `json:"refreshed"`
+	Files     map[string]*cachedFile `json:"files"`
+	// Directories where all children are known
+	Listed map[string]bool `json:"listed"`
+}
+
+// On-disk cache of file metadata used for path resolution.
+// The cache is kept up to date with the changes api, the
+// page token of the last seen change is stored with the cache
+type metadataCache struct {
+	path      string
+	mutex     *sync.Mutex
+	refreshed bool
+	dirty     bool
+	data      metadataCacheData
+	// Ids of the cached files by parent id
+	byParent map[string]map[string]bool
+}
+
+// Enables the on-disk metadata cache stored at the given path
+func (self *Drive) UseMetadataCache(path string) {
+	self.cache = loadMetadataCache(path)
+}
+
+func loadMetadataCache(path string) *metadataCache {
+	cache := &metadataCache{
+		path:  path,
+		mutex: &sync.Mutex{},
+	}
+
+	f, err := os.Open(path)
+	if err == nil {
+		json.NewDecoder(f).Decode(&cache.data)
+		f.Close()
+	}
+
+	// Start over if the cache is from another version
+	if cache.data.Version != MetadataCacheVersion {
+		cache.reset()
+	}
+
+	cache.indexParents()
+	return cache
+}
+
+// Returns the metadata cache, the cache is refreshed
+// the first time it is used. Nil is returned if the cache
+// is disabled or could not be refreshed
+func (self *Drive) metadataCache() *metadataCache {
+	if self.cache == nil {
+		return nil
+	}
+
+	if !self.cache.isRefreshed() {
+		if _, err := self.refreshMetadataCache(); err != nil {
+			return nil
+		}
+	}
+
+	return self.cache
+}
+
+var errInvalidPageToken = fmt.Errorf("Invalid page token")
+
+// Applies all changes since the last refresh, returns the number of changes
+func (self *Drive) refreshMetadataCache() (int, error) {
+	cache := self.cache
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	count, err := self.applyCacheChanges(cache)
+	if err == errInvalidPageToken {
+		// Start over with an empty cache
+		cache.reset()
+		count, err = self.applyCacheChanges(cache)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	cache.markRefreshed()
+	return count, cache.save()
+}
+
+func (self *Drive) applyCacheChanges(cache *metadataCache) (int, error) {
+	if cache.data.PageToken == "" {
+		token, err := self.GetChangesStartPageToken()
+		if err != nil {
+			return 0, err
+		}
+		cache.data.PageToken = token
+		return 0, nil
+	}
+
+	var count int
+	pageToken := cache.data.PageToken
+
+	for {
+		changeList, err := self.service.Changes.List(pageToken).PageSize(1000).Fields(cacheChangeFields...).Do()
+		if err != nil {
+			if ae, ok := err.(*googleapi.Error); ok && (ae.Code == 400 || ae.Code == 404) {
+				return 0, errInvalidPageToken
+			}
+			return 0, fmt.Errorf("Failed listing changes: %s", err)
+		}
+
+		for _, c := range changeList.Changes {
+			cache.applyChange(c)
+		}
+		count += len(changeList.Changes)
+
+		var hasMore bool
+		pageToken, hasMore = nextChangesPageToken(changeList)
+		if !hasMore {
+			break
+		}
+	}
+
+	cache.data.PageToken = pageToken
+	return count, nil
+}
+
+func (self *metadataCache) reset() {
+	self.data = metadataCacheData{
+		Version: MetadataCacheVersion,
+		Files:   map[string]*cachedFile{},
+		Listed:  map[string]bool{},
+	}
+	self.byParent = map[string]map[string]bool{}
+	self.dirty = true
+}
+
+func (self *metadataCache) indexParents() {
+	self.byParent = map[string]map[string]bool{}
+	for id, cf := range self.data.Files {
+		self.index(id, cf.Parents)
+	}
+}
+
+func (self *metadataCache) index(id string, parents []string) {
+	for _, parent := range parents {
+		if self.byParent[parent] == nil {
+			self.byParent[parent] = map[string]bool{}
+		}
+		self.byParent[parent][id] = true
+	}
+}
+
+func (self *metadataCache) unindex(id string) {
+	cf, ok := self.data.Files[id]
+	if !ok {
+		return
+	}
+	for _, parent := range cf.Parents {
+		delete(self.byParent[parent], id)
+		if len(self.byParent[parent]) == 0 {
+			delete(self.byParent, parent)
+		}
+	}
+}
+
+func (self *metadataCache) isRefreshed() bool {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.refreshed
+}
+
+func (self *metadataCache) markRefreshed() {
+	self.refreshed = true
+	self.data.Refreshed = time.Now().Format(time.RFC3339)
+	self.dirty = true
+}
+
+func (self *metadataCache) applyChange(c *drive.Change) {
+	if c.Removed || c.File == nil || c.File.Trashed {
+		self.remove(c.FileId)
+		return
+	}
+
+	// Only keep track of files that are already cached or
+	// that belong to a directory where all children are known
+	_, cached := self.data.Files[c.FileId]
+	if cached || self.hasListedParent(c.File) {
+		self.put(c.File)
+	}
+}
+
+func (self *metadataCache) hasListedParent(f *drive.File) bool {
+	for _, parent := range f.Parents {
+		if self.data.Listed[parent] {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *metadataCache) put(f *drive.File) {
+	self.unindex(f.Id)
+	self.index(f.Id, f.Parents)
+	self.data.Files[f.Id] = &cachedFile{
+		Id:          f.Id,
+		Name:        f.Name,
+		Parents:     f.Parents,
+		MimeType:    f.MimeType,
+		Md5Checksum: f.Md5Checksum,
+		Size:        f.Size,
+		CreatedTime: f.CreatedTime,
+	}
+	self.dirty = true
+}
+
+func (self *metadataCache) remove(id string) {
+	self.unindex(id)
+	delete(self.data.Files, id)
+	delete(self.data.Listed, id)
+	self.dirty = true
+}
+
+func (self *metadataCache) rootId() string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.data.RootId
+}
+
+func (self *metadataCache) setRootId(id string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.data.RootId = id
+	self.dirty = true
+}
+
+func (self *metadataCache) get(id string) (*drive.File, bool) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	cf, ok := self.data.Files[id]
+	if !ok {
+		return nil, false
+	}
+	return cf.file(), true
+}
+
+func (self *metadataCache) add(f *drive.File) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.put(f)
+}
+
+// Returns the children of the given directory, the second
+// return value is false if the children are not known
+func (self *metadataCache) children(parentId string) ([]*drive.File, bool) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if !self.data.Listed[parentId] {
+		return nil, false
+	}
+
+	var files []*drive.File
+	for id := range self.byParent[parentId] {
+		files = append(files, self.data.Files[id].file())
+	}
+	return files, true
+}
+
+// Replaces the known children of the given directory
+func (self *metadataCache) setChildren(parentId string, files []*drive.File) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	// Remove stale children
+	for id := range self.byParent[parentId] {
+		if len(self.data.Files[id].Parents) == 1 {
+			self.unindex(id)
+			delete(self.data.Files, id)
+		}
+	}
+
+	for _, f := range files {
+		self.put(f)
+	}
+	self.data.Listed[parentId] = true
+	self.dirty = true
+}
+
+// Writes the cache to disk if it has changed
+func (self *metadataCache) persist() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.save()
+}
+
+func (self *metadataCache) save() error {
+	if !self.dirty {
+		return nil
+	}
+
+	if err := mkdir(self.path); err != nil {
+		return err
+	}
+
+	tmpPath := self.path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("Failed to write metadata cache: %s", err)
+	}
+
+	err = json.NewEncoder(f).Encode(self.data)
+	f.Close()
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("Failed to write metadata cache: %s", err)
+	}
+
+	self.dirty = false
+	return os.Rename(tmpPath, self.path)
+}
+
+func (self *cachedFile) file() *drive.File {
+	return &drive.File{
+		Id:          self.Id,
+		Name:        self.Name,
+		Parents:     self.Parents,
+		MimeType:    self.MimeType,
+		Md5Checksum: self.Md5Checksum,
+		Size:        self.Size,
+		CreatedTime: self.CreatedTime,
+	}
+}
+
+type CacheStatusArgs struct {
+	Out         io.Writer
+	SizeInBytes bool
+}
+
+func (self *Drive) CacheStatus(args CacheStatusArgs) error {
+	if self.cache == nil {
+		return fmt.Errorf("Metadata cache is not enabled")
+	}
+
+	cache := self.cache
+
+	var size int64
+	if info, err := os.Stat(cache.path); err == nil {
+		size = info.Size()
+	}
+
+	items := []kv{
+		kv{"Path", cache.path},
+		kv{"Files", fmt.Sprintf("%d", len(cache.data.Files))},
+		kv{"Directories listed", fmt.Sprintf("%d", len(cache.data.Listed))},
+		kv{"Page token", cache.data.PageToken},
+		kv{"Refreshed", formatDatetime(cache.data.Refreshed)},
+		kv{"Size", formatSize(size, args.SizeInBytes)},
+	}
+
+	for _, item := range items {
+		if item.value != "" {
+			fmt.Fprintf(args.Out, "%s: %s\n", item.key, item.value)
+		}
+	}
+
+	return nil
+}
+
+type CacheRefreshArgs struct {
+	Out io.Writer
+}
+
+func (self *Drive) CacheRefresh(args CacheRefreshArgs) error {
+	if self.cache == nil {
+		return fmt.Errorf("Metadata cache is not enabled")
+	}
+
+	count, err := self.refreshMetadataCache()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Applied %d changes, %d files cached\n", count, len(self.cache.data.Files))
+	return nil
+}
+
+type CacheClearArgs struct {
+	Out io.Writer
+}
+
+func (self *Drive) CacheClear(args CacheClearArgs) error {
+	if self.cache == nil {
+		return fmt.Errorf("Metadata cache is not enabled")
+	}
+
+	err := os.Remove(self.cache.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Failed to remove metadata cache: %s", err)
+	}
+
+	self.cache.reset()
+	self.cache.dirty = false
+
+	fmt.Fprintln(args.Out, "Metadata cache cleared")
+	return nil
+}
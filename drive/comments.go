@@ -0,0 +1,218 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const commentFields = "id,author(displayName,emailAddress),content,createdTime,modifiedTime,resolved,deleted,anchor,quotedFileContent,replies(id,author(displayName,emailAddress),content,createdTime,modifiedTime,action,deleted)"
+
+type ListCommentsArgs struct {
+	Out            io.Writer
+	FileId         string
+	Unresolved     bool
+	IncludeDeleted bool
+	Format         string
+	ContentWidth   int64
+	SkipHeader     bool
+}
+
+func (args *ListCommentsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+// Lists the comments of the file with their replies. The json output
+// holds everything the api returns, including quoted content and anchors
+func (self *Drive) ListComments(args ListCommentsArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	format := strings.ToLower(args.Format)
+	if format != "table" && format != "json" {
+		return fmt.Errorf("Unknown output format '%s', valid formats are: table, json", args.Format)
+	}
+
+	var comments []*drive.Comment
+
+	call := self.service.Comments.List(args.FileId).IncludeDeleted(args.IncludeDeleted).PageSize(100)
+	err := call.Fields(googleapi.Field(fmt.Sprintf("nextPageToken,comments(%s)", commentFields))).Pages(self.ctx, func(cl *drive.CommentList) error {
+		for _, c := range cl.Comments {
+			if args.Unresolved && c.Resolved {
+				continue
+			}
+			comments = append(comments, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to list comments: %s", err)
+	}
+
+	if format == "json" {
+		if comments == nil {
+			comments = []*drive.Comment{}
+		}
+
+		enc := json.NewEncoder(args.Out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(comments); err != nil {
+			return fmt.Errorf("Failed to write comments: %s", err)
+		}
+		return nil
+	}
+
+	printComments(args, comments)
+	return nil
+}
+
+func printComments(args ListCommentsArgs, comments []*drive.Comment) {
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Id\tAuthor\tCreated\tResolved\tReplies\tQuoted\tContent")
+	}
+
+	width := int(args.ContentWidth)
+
+	for _, c := range comments {
+		var quoted string
+		if c.QuotedFileContent != nil {
+			quoted = c.QuotedFileContent.Value
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			c.Id,
+			commentAuthor(c.Author),
+			formatDatetime(c.CreatedTime),
+			formatBool(c.Resolved),
+			len(c.Replies),
+			truncateString(singleLine(quoted), width),
+			truncateString(singleLine(c.Content), width),
+		)
+	}
+
+	w.Flush()
+}
+
+func commentAuthor(u *drive.User) string {
+	if u == nil {
+		return ""
+	}
+	if u.EmailAddress != "" {
+		return u.EmailAddress
+	}
+	return u.DisplayName
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+type AddCommentArgs struct {
+	Out     io.Writer
+	FileId  string
+	Content string
+	// Text of the document the comment refers to
+	Quote string
+	// Region of the document the comment refers to, as json,
+	// see https://developers.google.com/drive/api/v3/manage-comments
+	Anchor string
+}
+
+func (args *AddCommentArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+func (self *Drive) AddComment(args AddCommentArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	comment := &drive.Comment{
+		Content: args.Content,
+		Anchor:  args.Anchor,
+	}
+
+	if args.Quote != "" {
+		comment.QuotedFileContent = &drive.CommentQuotedFileContent{
+			MimeType: "text/plain",
+			Value:    args.Quote,
+		}
+	}
+
+	c, err := self.service.Comments.Create(args.FileId, comment).Fields("id").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to add comment: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Comment %s added\n", c.Id)
+	return nil
+}
+
+type ReplyCommentArgs struct {
+	Out       io.Writer
+	FileId    string
+	CommentId string
+	Content   string
+	// Resolve the comment with the reply
+	Resolve bool
+}
+
+func (args *ReplyCommentArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+// Replies to the comment, resolving it if requested.
+// The content is optional when resolving
+func (self *Drive) ReplyComment(args ReplyCommentArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	if args.Content == "" && !args.Resolve {
+		return fmt.Errorf("A reply must have content")
+	}
+
+	reply := &drive.Reply{Content: args.Content}
+	if args.Resolve {
+		reply.Action = "resolve"
+	}
+
+	r, err := self.service.Replies.Create(args.FileId, args.CommentId, reply).Fields("id").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to reply to comment: %s", err)
+	}
+
+	if args.Resolve {
+		fmt.Fprintf(args.Out, "Comment %s resolved\n", args.CommentId)
+	} else {
+		fmt.Fprintf(args.Out, "Reply %s added\n", r.Id)
+	}
+	return nil
+}
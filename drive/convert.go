@@ -0,0 +1,214 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// Max time to wait for the temporary document to be deleted
+const ConvertCleanupTimeout = 30 * time.Second
+
+type ConvertArgs struct {
+	Out      io.Writer
+	Progress io.Writer
+	Path     string
+	// Format extension of the result, i.e. pdf
+	To string
+	// Output file, or output directory when converting a directory.
+	// Defaults to the path of the source with the new extension
+	Output  string
+	Force   bool
+	Timeout time.Duration
+}
+
+// Converts local files by importing them as google documents and
+// exporting them in the requested format. The imported documents
+// are only temporary and deleted after the export
+func (self *Drive) Convert(args ConvertArgs) error {
+	if args.To == "" {
+		return fmt.Errorf("Missing format, use --to")
+	}
+
+	to := strings.ToLower(strings.TrimPrefix(args.To, "."))
+	if getFormatMimeType(to) == "" {
+		return fmt.Errorf("Unknown format '%s'", args.To)
+	}
+
+	info, err := os.Stat(args.Path)
+	if err != nil {
+		return fmt.Errorf("Failed stat file: %s", err)
+	}
+
+	about, err := self.service.About.Get().Fields("importFormats").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get about: %s", err)
+	}
+
+	if info.IsDir() {
+		return self.convertDirectory(about.ImportFormats, to, args)
+	}
+
+	output := args.Output
+	if output == "" {
+		output = getConvertFilename(args.Path, to)
+	}
+
+	docMime, exportMime, err := getConvertMimes(about.ImportFormats, getMimeType(args.Path), to)
+	if err != nil {
+		return err
+	}
+
+	return self.convertFile(args.Path, output, docMime, exportMime, args)
+}
+
+// Converts all files in the directory tree, preserving the folder
+// structure below the output directory. Files that can not be
+// converted to the format are skipped
+func (self *Drive) convertDirectory(formats map[string][]string, to string, args ConvertArgs) error {
+	outDir := args.Output
+	if outDir == "" {
+		outDir = args.Path
+	}
+
+	// Collect the files first so the results written
+	// to the source directory are not converted again
+	var paths []string
+	err := filepath.Walk(args.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed reading directory: %s", err)
+	}
+
+	var skipped []skippedImport
+	var failed, converted int
+
+	for _, path := range paths {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if ext == to {
+			skipped = append(skipped, skippedImport{path, "already in target format"})
+			continue
+		}
+
+		fromMime := getMimeType(path)
+		if fromMime == "" {
+			skipped = append(skipped, skippedImport{path, "unknown mime type"})
+			continue
+		}
+
+		docMime, exportMime, err := getConvertMimes(formats, fromMime, to)
+		if err != nil {
+			skipped = append(skipped, skippedImport{path, err.Error()})
+			continue
+		}
+
+		relPath, err := filepath.Rel(args.Path, path)
+		if err != nil {
+			return fmt.Errorf("Failed to get relative path: %s", err)
+		}
+		output := filepath.Join(outDir, getConvertFilename(relPath, to))
+
+		converted++
+		if err := self.convertFile(path, output, docMime, exportMime, args); err != nil {
+			fmt.Fprintf(args.Out, "Failed to convert '%s': %s\n", path, err)
+			failed++
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(args.Out, "\n%d files were skipped:\n", len(skipped))
+		printSkippedImports(args.Out, skipped)
+	}
+
+	return batchError("convert", failed, converted)
+}
+
+func (self *Drive) convertFile(path, output, docMime, exportMime string, args ConvertArgs) error {
+	id, err := self.importFile(path, docMime, nil, ImportArgs{Progress: args.Progress})
+	if err != nil {
+		return err
+	}
+
+	// Remove the temporary document, also when the export fails or is
+	// interrupted. The root context is canceled when interrupted, so
+	// the request gets its own context
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ConvertCleanupTimeout)
+		defer cancel()
+
+		if err := self.service.Files.Delete(id).Context(ctx).Do(); err != nil {
+			fmt.Fprintf(args.Out, "Failed to delete temporary document %s: %s\n", id, err)
+		}
+	}()
+
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, args.Timeout)
+
+	res, err := self.service.Files.Export(id, exportMime).Context(ctx).Download()
+	if err != nil {
+		if self.isTimeoutError(err) {
+			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
+		}
+		return fmt.Errorf("Failed to download file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	_, _, err = self.saveFile(saveFileArgs{
+		out:           args.Out,
+		body:          timeoutReaderWrapper(res.Body),
+		contentLength: res.ContentLength,
+		fpath:         output,
+		force:         args.Force,
+		progress:      args.Progress,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Converted '%s' to '%s'\n", path, output)
+	return nil
+}
+
+// Returns the google document mime type to import the file as and the mime
+// type to export it with. The first document type supporting the format is used
+func getConvertMimes(formats map[string][]string, fromMime, to string) (string, string, error) {
+	if fromMime == "" {
+		return "", "", fmt.Errorf("Could not determine mime type of file")
+	}
+
+	docMimes, ok := formats[fromMime]
+	if !ok || len(docMimes) == 0 {
+		return "", "", fmt.Errorf("Mime type '%s' is not supported for import", fromMime)
+	}
+
+	for _, m := range docMimes {
+		if exportMime, ok := exportFormatMimes[m][to]; ok {
+			return m, exportMime, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("Mime type '%s' can not be converted to %s", fromMime, to)
+}
+
+// Replaces the extension of the path with the format
+func getConvertFilename(path, format string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + format
+}
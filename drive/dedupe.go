@@ -0,0 +1,314 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const (
+	DedupeList  = "list"
+	DedupeTrash = "trash"
+	DedupeLink  = "link"
+	DedupeNames = "names"
+)
+
+type DedupeArgs struct {
+	Out         io.Writer
+	Id          string
+	Mode        string
+	DryRun      bool
+	SizeInBytes bool
+}
+
+func (args *DedupeArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+// Files with the same content, or with the same name in the same
+// directory. The file to keep, the newest one, comes first
+type duplicateGroup struct {
+	files []*treeFile
+	paths []string
+}
+
+// Space that is freed when all but the first file are removed
+func (self *duplicateGroup) wasted() int64 {
+	return self.files[0].file.Size * int64(len(self.files)-1)
+}
+
+// Finds files with the same md5 and size in the directory tree. Depending
+// on the mode the duplicates are listed, trashed or replaced with shortcuts
+// to the newest copy. The names mode lists files with the same name in the
+// same directory, which makes paths to them ambiguous
+func (self *Drive) Dedupe(args DedupeArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	switch args.Mode {
+	case DedupeList, DedupeTrash, DedupeLink, DedupeNames:
+	default:
+		return fmt.Errorf("Unknown mode '%s', valid modes are: list, trash, link, names", args.Mode)
+	}
+
+	tree, err := self.fileTree([]string{args.Id}, "id,name,mimeType,md5Checksum,size,modifiedTime,parents")
+	if err != nil {
+		return err
+	}
+
+	var groups []*duplicateGroup
+	if args.Mode == DedupeNames {
+		groups = sameNameGroups(tree)
+	} else {
+		groups = duplicateContentGroups(tree)
+	}
+
+	if len(groups) == 0 {
+		fmt.Fprintln(args.Out, "No duplicates found")
+		return nil
+	}
+
+	if err := self.resolveDuplicatePaths(groups); err != nil {
+		return err
+	}
+
+	printDuplicateGroups(args.Out, groups, args.SizeInBytes)
+
+	if args.Mode == DedupeNames {
+		fmt.Fprintf(args.Out, "%d names are used more than once\n", len(groups))
+		return nil
+	}
+
+	var count int
+	var wasted int64
+	for _, g := range groups {
+		count += len(g.files) - 1
+		wasted += g.wasted()
+	}
+
+	fmt.Fprintf(args.Out, "%d duplicates in %d groups, %s wasted\n", count, len(groups), formatSize(wasted, args.SizeInBytes))
+
+	if args.Mode == DedupeList || args.DryRun {
+		return nil
+	}
+
+	if args.Mode == DedupeLink {
+		return self.linkDuplicates(args.Out, groups, count)
+	}
+	return self.trashDuplicates(args.Out, groups, count)
+}
+
+// Groups files by md5 and size, files without checksum like
+// directories and google documents are ignored
+func duplicateContentGroups(tree []*treeFile) []*duplicateGroup {
+	return groupTreeFiles(tree, func(tf *treeFile) string {
+		if tf.file.Md5Checksum == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s:%d", tf.file.Md5Checksum, tf.file.Size)
+	})
+}
+
+// Groups files by directory and name, the given root is ignored
+func sameNameGroups(tree []*treeFile) []*duplicateGroup {
+	return groupTreeFiles(tree, func(tf *treeFile) string {
+		if tf.parent == nil {
+			return ""
+		}
+		return tf.parent.file.Id + "/" + tf.file.Name
+	})
+}
+
+// Returns the groups of files with the same key that have more than one
+// file, files with an empty key are ignored. The groups wasting the most
+// space come first
+func groupTreeFiles(tree []*treeFile, key func(*treeFile) string) []*duplicateGroup {
+	byKey := map[string]*duplicateGroup{}
+	var groups []*duplicateGroup
+
+	// Files with several parents show up more than once
+	seen := map[string]bool{}
+
+	for _, tf := range tree {
+		k := key(tf)
+		if k == "" || seen[tf.file.Id] {
+			continue
+		}
+		seen[tf.file.Id] = true
+
+		g, found := byKey[k]
+		if !found {
+			g = &duplicateGroup{}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		g.files = append(g.files, tf)
+	}
+
+	var duplicates []*duplicateGroup
+	for _, g := range groups {
+		if len(g.files) > 1 {
+			sort.Stable(sort.Reverse(byTreeFileModifiedTime(g.files)))
+			duplicates = append(duplicates, g)
+		}
+	}
+
+	sort.Stable(sort.Reverse(byDuplicatesWasted(duplicates)))
+	return duplicates
+}
+
+func (self *Drive) resolveDuplicatePaths(groups []*duplicateGroup) error {
+	finder := self.newPathFinder()
+
+	var files []*drive.File
+	for _, g := range groups {
+		for _, tf := range g.files {
+			files = append(files, tf.file)
+		}
+	}
+
+	if err := finder.PrefetchParents(files); err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		for _, tf := range g.files {
+			absPath, err := finder.GetAbsPath(tf.file)
+			if err != nil {
+				return err
+			}
+			g.paths = append(g.paths, absPath)
+		}
+	}
+
+	return nil
+}
+
+func printDuplicateGroups(out io.Writer, groups []*duplicateGroup, sizeInBytes bool) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Id\tSize\tModified\tPath")
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(w, "\t\t\t")
+		}
+
+		for j, tf := range g.files {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				tf.file.Id,
+				formatSize(tf.file.Size, sizeInBytes),
+				formatDatetime(tf.file.ModifiedTime),
+				g.paths[j],
+			)
+		}
+	}
+
+	w.Flush()
+}
+
+// Moves all but the newest file of each group to the trash
+func (self *Drive) trashDuplicates(out io.Writer, groups []*duplicateGroup, count int) error {
+	var requests []*batchRequest
+	var paths []string
+
+	for _, g := range groups {
+		for i, tf := range g.files[1:] {
+			requests = append(requests, trashRequest(tf.file.Id))
+			paths = append(paths, g.paths[i+1])
+		}
+	}
+
+	var failed int
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(out, "Failed to trash '%s': %s\n", paths[i], result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "Trashed '%s'\n", paths[i])
+	}
+
+	return batchError("trash", failed, count)
+}
+
+// Replaces all but the newest file of each group with a shortcut to the
+// newest file. A duplicate is only trashed after its shortcut is created
+func (self *Drive) linkDuplicates(out io.Writer, groups []*duplicateGroup, count int) error {
+	var requests []*batchRequest
+	var duplicates []*treeFile
+	var paths []string
+
+	for _, g := range groups {
+		for i, tf := range g.files[1:] {
+			requests = append(requests, shortcutRequest(tf.file.Name, g.files[0].file.Id, tf.parent.file.Id))
+			duplicates = append(duplicates, tf)
+			paths = append(paths, g.paths[i+1])
+		}
+	}
+
+	var failed int
+	var trashRequests []*batchRequest
+	var trashPaths []string
+
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(out, "Failed to create shortcut for '%s': %s\n", paths[i], result.err)
+			failed++
+			continue
+		}
+		trashRequests = append(trashRequests, trashRequest(duplicates[i].file.Id))
+		trashPaths = append(trashPaths, paths[i])
+	}
+
+	for i, result := range self.executeBatch(trashRequests) {
+		if result.err != nil {
+			fmt.Fprintf(out, "Created shortcut but failed to trash '%s': %s\n", trashPaths[i], result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(out, "Replaced '%s' with shortcut\n", trashPaths[i])
+	}
+
+	return batchError("replace", failed, count)
+}
+
+type byTreeFileModifiedTime []*treeFile
+
+func (self byTreeFileModifiedTime) Len() int {
+	return len(self)
+}
+
+func (self byTreeFileModifiedTime) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+// RFC 3339 timestamps in UTC sort lexically
+func (self byTreeFileModifiedTime) Less(i, j int) bool {
+	return self[i].file.ModifiedTime < self[j].file.ModifiedTime
+}
+
+type byDuplicatesWasted []*duplicateGroup
+
+func (self byDuplicatesWasted) Len() int {
+	return len(self)
+}
+
+func (self byDuplicatesWasted) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byDuplicatesWasted) Less(i, j int) bool {
+	return self[i].wasted() < self[j].wasted()
+}
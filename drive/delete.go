@@ -3,6 +3,7 @@ package drive
 import (
 	"fmt"
 	"io"
+	"net/url"
 )
 
 type DeleteArgs struct {
@@ -11,14 +12,30 @@ type DeleteArgs struct {
 	Recursive bool
 }
 
-func (args *DeleteArgs) normalize(drive *Drive) {
-	finder := drive.newPathFinder()
-	args.Id = finder.SecureFileId(args.Id)
-}
-
 func (self *Drive) Delete(args DeleteArgs) error {
-	args.normalize(self)
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) > 1 {
+		return self.deleteBatch(ids, args)
+	}
+
+	for _, id := range ids {
+		fileArgs := args
+		fileArgs.Id = id
+
+		err = self.deleteOne(fileArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+func (self *Drive) deleteOne(args DeleteArgs) error {
 	f, err := self.service.Files.Get(args.Id).Fields("name", "mimeType").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
@@ -37,6 +54,51 @@ func (self *Drive) Delete(args DeleteArgs) error {
 	return nil
 }
 
+// Deletes multiple files using batch requests
+func (self *Drive) deleteBatch(ids []string, args DeleteArgs) error {
+	files, errors := self.batchGetFiles(ids, "id", "name", "mimeType")
+
+	var deleteIds []string
+	var names []string
+	var failed int
+
+	for i, f := range files {
+		if errors[i] != nil {
+			fmt.Fprintf(args.Out, "Failed to get file '%s': %s\n", ids[i], errors[i])
+			failed++
+			continue
+		}
+
+		if isDir(f) && !args.Recursive {
+			fmt.Fprintf(args.Out, "Skipping '%s', it is a directory, use the 'recursive' flag to delete directories\n", f.Name)
+			failed++
+			continue
+		}
+
+		deleteIds = append(deleteIds, f.Id)
+		names = append(names, f.Name)
+	}
+
+	var requests []*batchRequest
+	for _, id := range deleteIds {
+		requests = append(requests, &batchRequest{
+			method: "DELETE",
+			path:   "files/" + url.PathEscape(id),
+		})
+	}
+
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(args.Out, "Failed to delete '%s': %s\n", names[i], result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(args.Out, "Deleted '%s'\n", names[i])
+	}
+
+	return batchError("delete", failed, len(ids))
+}
+
 func (self *Drive) deleteFile(fileId string) error {
 	err := self.service.Files.Delete(fileId).Do()
 	if err != nil {
@@ -22,23 +22,42 @@ type DownloadArgs struct {
 	Delete    bool
 	Stdout    bool
 	Timeout   time.Duration
-}
 
-func (args *DownloadArgs) normalize(drive *Drive) {
-	finder := drive.newPathFinder()
-	args.Id = finder.SecureFileId(args.Id)
+	// Directories being downloaded, used to detect shortcut cycles
+	ancestors map[string]bool
 }
 
 func (self *Drive) Download(args DownloadArgs) error {
-	args.normalize(self)
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		fileArgs := args
+		fileArgs.Id = id
+
+		err = self.downloadOne(fileArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+func (self *Drive) downloadOne(args DownloadArgs) error {
 	if args.Recursive {
 		return self.downloadRecursive(args)
 	}
 
-	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "mimeType", "md5Checksum").Do()
+	f, err := self.getDownloadFile(args.Id)
 	if err != nil {
-		return fmt.Errorf("Failed to get file: %s", err)
+		return err
 	}
 
 	if isDir(f) {
@@ -75,6 +94,8 @@ type DownloadQueryArgs struct {
 	Out       io.Writer
 	Progress  io.Writer
 	Query     string
+	Filter    QueryFilter
+	ShowQuery bool
 	Path      string
 	Force     bool
 	Skip      bool
@@ -88,8 +109,18 @@ func (args *DownloadQueryArgs) normalize(drive *Drive) {
 func (self *Drive) DownloadQuery(args DownloadQueryArgs) error {
 	args.normalize(self)
 
+	query, err := self.buildQuery(args.Query, args.Filter)
+	if err != nil {
+		return err
+	}
+
+	if args.ShowQuery {
+		fmt.Fprintln(args.Out, query)
+		return nil
+	}
+
 	listArgs := listAllFilesArgs{
-		query:  args.Query,
+		query:  query,
 		fields: []googleapi.Field{"nextPageToken", "files(id,name,mimeType,size,md5Checksum)"},
 	}
 	files, err := self.listAllFiles(listArgs)
@@ -105,11 +136,31 @@ func (self *Drive) DownloadQuery(args DownloadQueryArgs) error {
 		Skip:     args.Skip,
 	}
 
+	return self.downloadFiles(files, args.Recursive, downloadArgs)
+}
+
+// Downloads the binary files, directories are downloaded if recursive is
+// given, documents are skipped and shortcuts are followed. The files need id, name, mimeType, size
+// and md5Checksum
+func (self *Drive) downloadFiles(files []*drive.File, recursive bool, args DownloadArgs) error {
+	var err error
+
 	for _, f := range files {
-		if isDir(f) && args.Recursive {
-			err = self.downloadDirectory(f, downloadArgs)
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		if isShortcut(f) {
+			f, err = self.getDownloadFile(f.Id)
+			if err != nil {
+				return err
+			}
+		}
+
+		if isDir(f) && recursive {
+			err = self.downloadDirectory(f, args)
 		} else if isBinary(f) {
-			_, _, err = self.downloadBinary(f, downloadArgs)
+			_, _, err = self.downloadBinary(f, args)
 		}
 
 		if err != nil {
@@ -120,10 +171,33 @@ func (self *Drive) DownloadQuery(args DownloadQueryArgs) error {
 	return nil
 }
 
+// Returns the file with the fields needed for download. Shortcuts are
+// replaced by their target, which keeps the name of the shortcut
+func (self *Drive) getDownloadFile(id string) (*drive.File, error) {
+	fields := []googleapi.Field{"id", "name", "size", "mimeType", "md5Checksum"}
+
+	f, err := self.service.Files.Get(id).Fields(fields...).Do()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	if !isShortcut(f) {
+		return f, nil
+	}
+
+	target, err := self.shortcutTarget(f, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	target.Name = f.Name
+	return target, nil
+}
+
 func (self *Drive) downloadRecursive(args DownloadArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "mimeType", "md5Checksum").Do()
+	f, err := self.getDownloadFile(args.Id)
 	if err != nil {
-		return fmt.Errorf("Failed to get file: %s", err)
+		return err
 	}
 
 	if isDir(f) {
@@ -138,11 +212,11 @@ func (self *Drive) downloadRecursive(args DownloadArgs) error {
 
 func (self *Drive) downloadBinary(f *drive.File, args DownloadArgs) (int64, int64, error) {
 	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(args.Timeout)
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, args.Timeout)
 
 	res, err := self.service.Files.Get(f.Id).Context(ctx).Download()
 	if err != nil {
-		if isTimeoutError(err) {
+		if self.isTimeoutError(err) {
 			return 0, 0, fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
 		}
 		return 0, 0, fmt.Errorf("Failed to download file: %s", err)
@@ -198,7 +272,7 @@ func (self *Drive) saveFile(args saveFileArgs) (int64, int64, error) {
 
 	//Check if file exists to skip
 	if args.skip && fileExists(args.fpath) {
-		fmt.Printf("File '%s' already exists, skipping\n", args.fpath)
+		fmt.Fprintf(args.out, "File '%s' already exists, skipping\n", args.fpath)
 		return 0, 0, nil
 	}
 
@@ -237,6 +311,18 @@ func (self *Drive) saveFile(args saveFileArgs) (int64, int64, error) {
 }
 
 func (self *Drive) downloadDirectory(parent *drive.File, args DownloadArgs) error {
+	// A shortcut to a directory that is being downloaded would never end
+	if args.ancestors[parent.Id] {
+		fmt.Fprintf(args.Out, "Skipping '%s', shortcut cycle detected\n", filepath.Join(args.Path, parent.Name))
+		return nil
+	}
+
+	if args.ancestors == nil {
+		args.ancestors = map[string]bool{}
+	}
+	args.ancestors[parent.Id] = true
+	defer delete(args.ancestors, parent.Id)
+
 	listArgs := listAllFilesArgs{
 		query:  fmt.Sprintf("'%s' in parents", parent.Id),
 		fields: []googleapi.Field{"nextPageToken", "files(id,name)"},
@@ -249,6 +335,10 @@ func (self *Drive) downloadDirectory(parent *drive.File, args DownloadArgs) erro
 	newPath := filepath.Join(args.Path, parent.Name)
 
 	for _, f := range files {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		// Copy args and update changed fields
 		newArgs := args
 		newArgs.Path = newPath
@@ -1,19 +1,31 @@
 package drive
 
 import (
+	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"net/http"
 )
 
 type Drive struct {
 	service *drive.Service
+	client  *http.Client
+	retries *retryPolicy
+	cache   *metadataCache
+	ctx     context.Context
 }
 
 func New(client *http.Client) (*Drive, error) {
-	service, err := drive.New(client)
+	self := &Drive{
+		retries: &retryPolicy{maxRetries: DefaultMaxRetries},
+		ctx:     context.Background(),
+	}
+	self.client = newRetryClient(client, self.retries, self.rootContext)
+
+	service, err := drive.New(self.client)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Drive{service}, nil
+	self.service = service
+	return self, nil
 }
@@ -0,0 +1,250 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+type DiskUsageArgs struct {
+	Out         io.Writer
+	Id          string
+	Depth       int64
+	Top         int64
+	ByOwner     bool
+	ByMimeType  bool
+	SizeInBytes bool
+}
+
+func (args *DiskUsageArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+// Usage of a directory and everything below it
+type dirUsage struct {
+	path  string
+	depth int
+	size  int64
+	files int64
+	// Google documents do not have a size, they are counted separately
+	docs int64
+}
+
+// Usage grouped by owner or mime type
+type groupUsage struct {
+	name  string
+	files int64
+	quota int64
+}
+
+// Prints the size of each directory in the tree down to the given depth,
+// optionally followed by the largest files and the quota used per owner
+// and mime type
+func (self *Drive) DiskUsage(args DiskUsageArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	tree, err := self.fileTree([]string{args.Id}, "id,name,mimeType,md5Checksum,size,quotaBytesUsed,owners(emailAddress)")
+	if err != nil {
+		return err
+	}
+
+	if !isDir(tree[0].file) {
+		return fmt.Errorf("'%s' is not a directory", tree[0].file.Name)
+	}
+
+	dirs := map[*treeFile]*dirUsage{}
+	var files []*treeFile
+
+	for _, tf := range tree {
+		if isDir(tf.file) {
+			dirs[tf] = &dirUsage{path: tf.path, depth: treeDepth(tf)}
+			continue
+		}
+
+		files = append(files, tf)
+		for parent := tf.parent; parent != nil; parent = parent.parent {
+			usage := dirs[parent]
+			if isBinary(tf.file) {
+				usage.files++
+				usage.size += tf.file.Size
+			} else {
+				usage.docs++
+			}
+		}
+	}
+
+	var usages []*dirUsage
+	for _, usage := range dirs {
+		if usage.depth <= int(args.Depth) {
+			usages = append(usages, usage)
+		}
+	}
+	sort.Sort(byDirUsagePath(usages))
+
+	printDirUsage(args.Out, usages, args.SizeInBytes)
+
+	if args.Top > 0 {
+		fmt.Fprintln(args.Out)
+		printLargestFiles(args.Out, files, int(args.Top), args.SizeInBytes)
+	}
+
+	if args.ByOwner {
+		fmt.Fprintln(args.Out)
+		printGroupUsage(args.Out, "Owner", groupFiles(files, fileOwner), args.SizeInBytes)
+	}
+
+	if args.ByMimeType {
+		fmt.Fprintln(args.Out)
+		printGroupUsage(args.Out, "Mime", groupFiles(files, fileMimeType), args.SizeInBytes)
+	}
+
+	return nil
+}
+
+func treeDepth(tf *treeFile) int {
+	depth := 0
+	for parent := tf.parent; parent != nil; parent = parent.parent {
+		depth++
+	}
+	return depth
+}
+
+func printDirUsage(out io.Writer, usages []*dirUsage, sizeInBytes bool) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Size\tFiles\tDocs\tPath")
+
+	for _, u := range usages {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n",
+			formatSize(u.size, sizeInBytes),
+			u.files,
+			u.docs,
+			u.path,
+		)
+	}
+
+	w.Flush()
+}
+
+func printLargestFiles(out io.Writer, files []*treeFile, count int, sizeInBytes bool) {
+	sorted := make([]*treeFile, len(files))
+	copy(sorted, files)
+	sort.Stable(sort.Reverse(byTreeFileSize(sorted)))
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Id\tSize\tPath")
+
+	for _, tf := range sorted[:min(count, len(sorted))] {
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			tf.file.Id,
+			formatSize(tf.file.Size, sizeInBytes),
+			tf.path,
+		)
+	}
+
+	w.Flush()
+}
+
+func fileOwner(tf *treeFile) string {
+	if len(tf.file.Owners) == 0 {
+		return "unknown"
+	}
+	return tf.file.Owners[0].EmailAddress
+}
+
+func fileMimeType(tf *treeFile) string {
+	return tf.file.MimeType
+}
+
+// Groups the files by the given key, the largest groups come first
+func groupFiles(files []*treeFile, key func(*treeFile) string) []*groupUsage {
+	groups := map[string]*groupUsage{}
+	var usages []*groupUsage
+
+	for _, tf := range files {
+		name := key(tf)
+		usage, found := groups[name]
+		if !found {
+			usage = &groupUsage{name: name}
+			groups[name] = usage
+			usages = append(usages, usage)
+		}
+
+		usage.files++
+		usage.quota += tf.file.QuotaBytesUsed
+	}
+
+	sort.Stable(sort.Reverse(byGroupQuota(usages)))
+	return usages
+}
+
+func printGroupUsage(out io.Writer, title string, usages []*groupUsage, sizeInBytes bool) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintf(w, "%s\tFiles\tQuota used\n", title)
+
+	for _, u := range usages {
+		fmt.Fprintf(w, "%s\t%d\t%s\n",
+			u.name,
+			u.files,
+			formatSize(u.quota, sizeInBytes),
+		)
+	}
+
+	w.Flush()
+}
+
+type byDirUsagePath []*dirUsage
+
+func (self byDirUsagePath) Len() int {
+	return len(self)
+}
+
+func (self byDirUsagePath) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byDirUsagePath) Less(i, j int) bool {
+	return self[i].path < self[j].path
+}
+
+type byTreeFileSize []*treeFile
+
+func (self byTreeFileSize) Len() int {
+	return len(self)
+}
+
+func (self byTreeFileSize) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byTreeFileSize) Less(i, j int) bool {
+	return self[i].file.Size < self[j].file.Size
+}
+
+type byGroupQuota []*groupUsage
+
+func (self byGroupQuota) Len() int {
+	return len(self)
+}
+
+func (self byGroupQuota) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byGroupQuota) Less(i, j int) bool {
+	return self[i].quota < self[j].quota
+}
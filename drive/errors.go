@@ -3,10 +3,10 @@ package drive
 import (
 	"golang.org/x/net/context"
 	"google.golang.org/api/googleapi"
-	"time"
 )
 
-const MaxErrorRetries = 5
+// Error reasons returned by the api when a quota is exceeded
+var rateLimitReasons = []string{"rateLimitExceeded", "userRateLimitExceeded"}
 
 func isBackendOrRateLimitError(err error) bool {
 	return isBackendError(err) || isRateLimitError(err)
@@ -21,20 +21,39 @@ func isBackendError(err error) bool {
 	return ok && ae.Code >= 500 && ae.Code <= 599
 }
 
+// Rate limiting is reported as 429 or as 403 with a rate limit reason,
+// other 403 errors are permission errors and should not be retried
 func isRateLimitError(err error) bool {
 	if err == nil {
 		return false
 	}
 
 	ae, ok := err.(*googleapi.Error)
-	return ok && ae.Code == 403
+	if !ok {
+		return false
+	}
+
+	if ae.Code == 429 {
+		return true
+	}
+
+	return ae.Code == 403 && hasRateLimitReason(ae)
 }
 
-func isTimeoutError(err error) bool {
-	return err == context.Canceled
+func hasRateLimitReason(ae *googleapi.Error) bool {
+	for _, item := range ae.Errors {
+		for _, reason := range rateLimitReasons {
+			if item.Reason == reason {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-func exponentialBackoffSleep(try int) {
-	seconds := pow(2, try)
-	time.Sleep(time.Duration(seconds) * time.Second)
+// The timeout reader cancels the request context when no data is
+// transferred, which can not be told apart from the root context
+// being canceled, other than by checking the root context
+func (self *Drive) isTimeoutError(err error) bool {
+	return err == context.Canceled && !self.interrupted()
 }
@@ -5,6 +5,12 @@ import (
 	"io"
 	"mime"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
 var DefaultExportMime = map[string]string{
@@ -16,16 +22,85 @@ var DefaultExportMime = map[string]string{
 	"application/vnd.google-apps.presentation": "application/pdf",
 }
 
+// Export formats supported per google document type,
+// keyed by the file extension used for the exported file
+var exportFormatMimes = map[string]map[string]string{
+	"application/vnd.google-apps.document": {
+		"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"odt":  "application/vnd.oasis.opendocument.text",
+		"pdf":  "application/pdf",
+		"rtf":  "application/rtf",
+		"txt":  "text/plain",
+		"html": "text/html",
+		"epub": "application/epub+zip",
+	},
+	"application/vnd.google-apps.spreadsheet": {
+		"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"ods":  "application/vnd.oasis.opendocument.spreadsheet",
+		"pdf":  "application/pdf",
+		"csv":  "text/csv",
+		"tsv":  "text/tab-separated-values",
+	},
+	"application/vnd.google-apps.presentation": {
+		"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"odp":  "application/vnd.oasis.opendocument.presentation",
+		"pdf":  "application/pdf",
+		"txt":  "text/plain",
+	},
+	"application/vnd.google-apps.drawing": {
+		"pdf": "application/pdf",
+		"png": "image/png",
+		"jpg": "image/jpeg",
+		"svg": "image/svg+xml",
+	},
+}
+
+// Export format families, each family is a list of formats
+// in order of preference, see exportFormatMimes
+var exportFormatFamilies = map[string][]string{
+	"office": {"docx", "xlsx", "pptx", "pdf"},
+	"odf":    {"odt", "ods", "odp", "pdf"},
+	"pdf":    {"pdf"},
+}
+
 type ExportArgs struct {
 	Out        io.Writer
+	Progress   io.Writer
 	Id         string
 	PrintMimes bool
 	Mime       string
+	Format     string
+	Path       string
 	Force      bool
+	Recursive  bool
+	Timeout    time.Duration
 }
 
 func (self *Drive) Export(args ExportArgs) error {
-	f, err := self.service.Files.Get(args.Id).Fields("name", "mimeType").Do()
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		fileArgs := args
+		fileArgs.Id = id
+
+		err = self.exportOne(fileArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *Drive) exportOne(args ExportArgs) error {
+	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "mimeType", "md5Checksum", "size").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
 	}
@@ -34,42 +109,123 @@ func (self *Drive) Export(args ExportArgs) error {
 		return self.printMimes(args.Out, f.MimeType)
 	}
 
-	exportMime, err := getExportMime(args.Mime, f.MimeType)
+	if args.Mime != "" && args.Format != "" {
+		return fmt.Errorf("--mime and --format can not be used together")
+	}
+
+	exportMimes, err := getExportFormatFamily(args.Format)
 	if err != nil {
 		return err
 	}
 
-	filename := getExportFilename(f.Name, exportMime)
+	if args.Recursive {
+		if args.Mime != "" {
+			return fmt.Errorf("--mime is not allowed for recursive exports, use --format")
+		}
+		return self.exportRecursive(f, exportMimes, args)
+	}
 
-	res, err := self.service.Files.Export(args.Id, exportMime).Download()
+	if isDir(f) {
+		return fmt.Errorf("'%s' is a directory, use --recursive to export directories", f.Name)
+	}
+
+	userMime := args.Mime
+	if userMime == "" {
+		userMime = exportMimes[f.MimeType]
+	}
+
+	exportMime, err := getExportMime(userMime, f.MimeType)
+	if err != nil {
+		return err
+	}
+
+	return self.exportFile(f, exportMime, args)
+}
+
+func (self *Drive) exportFile(f *drive.File, exportMime string, args ExportArgs) error {
+	filename := filepath.Join(args.Path, getExportFilename(f.Name, exportMime))
+
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, args.Timeout)
+
+	res, err := self.service.Files.Export(f.Id, exportMime).Context(ctx).Download()
 	if err != nil {
+		if self.isTimeoutError(err) {
+			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
+		}
 		return fmt.Errorf("Failed to download file: %s", err)
 	}
 
 	// Close body on function exit
 	defer res.Body.Close()
 
-	// Check if file exists
-	if !args.Force && fileExists(filename) {
-		return fmt.Errorf("File '%s' already exists, use --force to overwrite", filename)
+	_, _, err = self.saveFile(saveFileArgs{
+		out:           args.Out,
+		body:          timeoutReaderWrapper(res.Body),
+		contentLength: res.ContentLength,
+		fpath:         filename,
+		force:         args.Force,
+		progress:      args.Progress,
+	})
+	if err != nil {
+		return err
 	}
 
-	// Create new file
-	outFile, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("Unable to create new file '%s': %s", filename, err)
+	fmt.Fprintf(args.Out, "Exported '%s' with mime type: '%s'\n", filename, exportMime)
+	return nil
+}
+
+// Exports all google documents and downloads all binary
+// files in the directory tree, preserving the folder structure
+func (self *Drive) exportRecursive(f *drive.File, exportMimes map[string]string, args ExportArgs) error {
+	if isBinary(f) {
+		_, _, err := self.downloadBinary(f, DownloadArgs{
+			Out:      args.Out,
+			Progress: args.Progress,
+			Path:     args.Path,
+			Force:    args.Force,
+			Timeout:  args.Timeout,
+		})
+		return err
 	}
 
-	// Close file on function exit
-	defer outFile.Close()
+	if !isDir(f) {
+		exportMime, err := getExportMime(exportMimes[f.MimeType], f.MimeType)
+		if err != nil {
+			fmt.Fprintf(args.Out, "Skipping '%s', %s\n", filepath.Join(args.Path, f.Name), err)
+			return nil
+		}
+		return self.exportFile(f, exportMime, args)
+	}
 
-	// Save file to disk
-	_, err = io.Copy(outFile, res.Body)
+	listArgs := listAllFilesArgs{
+		query:  fmt.Sprintf("trashed = false and '%s' in parents", f.Id),
+		fields: []googleapi.Field{"nextPageToken", "files(id,name,mimeType,md5Checksum,size)"},
+	}
+	files, err := self.listAllFiles(listArgs)
 	if err != nil {
-		return fmt.Errorf("Failed saving file: %s", err)
+		return fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	newArgs := args
+	newArgs.Path = filepath.Join(args.Path, f.Name)
+
+	// Ensure empty directories are created as well
+	if err := os.MkdirAll(newArgs.Path, 0775); err != nil {
+		return fmt.Errorf("Failed to create directory: %s", err)
+	}
+
+	for _, child := range files {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		err = self.exportRecursive(child, exportMimes, newArgs)
+		if err != nil {
+			return err
+		}
 	}
 
-	fmt.Fprintf(args.Out, "Exported '%s' with mime type: '%s'\n", filename, exportMime)
 	return nil
 }
 
@@ -102,10 +258,86 @@ func getExportMime(userMime, fileMime string) (string, error) {
 }
 
 func getExportFilename(name, mimeType string) string {
+	ext := getExportExtension(mimeType)
+	if ext == "" {
+		return name
+	}
+
+	return name + ext
+}
+
+func getExportExtension(mimeType string) string {
+	// Prefer our own extensions as the system mime database
+	// often lacks the office and opendocument formats
+	for _, formats := range exportFormatMimes {
+		for ext, m := range formats {
+			if m == mimeType {
+				return "." + ext
+			}
+		}
+	}
+
 	extensions, err := mime.ExtensionsByType(mimeType)
 	if err != nil || len(extensions) == 0 {
-		return name
+		return ""
+	}
+
+	return extensions[0]
+}
+
+// Returns the mime type of the given format (file extension), empty if unknown
+func getFormatMimeType(format string) string {
+	for _, formats := range exportFormatMimes {
+		if m, ok := formats[format]; ok {
+			return m
+		}
+	}
+	return ""
+}
+
+// Returns the export mime types of the given format family, see exportFormatFamilies
+func getExportFormatFamily(family string) (map[string]string, error) {
+	if family == "" {
+		return map[string]string{}, nil
+	}
+
+	formats, ok := exportFormatFamilies[strings.ToLower(family)]
+	if !ok {
+		return nil, fmt.Errorf("Unknown export format '%s', valid formats are: office, odf, pdf", family)
+	}
+
+	return parseExportFormats(formats)
+}
+
+// Takes a list of export formats (file extensions, i.e. docx,xlsx,pptx) and returns
+// a map of google document mime type -> export mime type. The first format
+// in the list that is supported by a document type is used for that type
+func parseExportFormats(formats []string) (map[string]string, error) {
+	exportMimes := map[string]string{}
+
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+		if format == "" {
+			continue
+		}
+
+		supported := false
+		for fileMime, formatMimes := range exportFormatMimes {
+			exportMime, ok := formatMimes[format]
+			if !ok {
+				continue
+			}
+			supported = true
+
+			if _, found := exportMimes[fileMime]; !found {
+				exportMimes[fileMime] = exportMime
+			}
+		}
+
+		if !supported {
+			return nil, fmt.Errorf("Unknown export format '%s'", format)
+		}
 	}
 
-	return name + extensions[0]
+	return exportMimes, nil
 }
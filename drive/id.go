@@ -15,6 +15,19 @@ func (self *Drive) Id(args IdArgs) error {
 	//fmt.Fprintf(args.Out, "AbsPath='%v', Error='%v'\n", args.AbsPath, args.Error)
 
 	finder := self.newPathFinder()
+
+	// Print one id per line when the path is a pattern
+	if hasGlobMeta(args.AbsPath) {
+		ids, err := finder.ResolveFileIds(args.AbsPath)
+		if err != nil && args.Error == true {
+			return err
+		}
+		for _, id := range ids {
+			fmt.Fprintln(args.Out, id)
+		}
+		return nil
+	}
+
 	Id, err := finder.GetFileId(args.AbsPath)
 	if err != nil && args.Error == true {
 		return err
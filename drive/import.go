@@ -5,19 +5,59 @@ import (
 	"io"
 	"io/ioutil"
 	"mime"
+	"os"
 	"path/filepath"
 	"strings"
+	"text/tabwriter"
 )
 
 type ImportArgs struct {
-	Out      io.Writer
-	Mime     string
-	Progress io.Writer
-	Path     string
-	Parents  []string
+	Out         io.Writer
+	Mime        string
+	To          string
+	OcrLanguage string
+	Progress    io.Writer
+	Path        string
+	Parents     []string
+	Recursive   bool
+}
+
+func (args *ImportArgs) normalize(drive *Drive) error {
+	ids, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
+	}
+
+	args.Parents = ids
+	return nil
 }
 
 func (self *Drive) Import(args ImportArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	about, err := self.service.About.Get().Fields("importFormats").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get about: %s", err)
+	}
+
+	if args.Recursive {
+		if args.Mime != "" {
+			return fmt.Errorf("--mime is not allowed for recursive imports")
+		}
+		return self.importRecursive(about.ImportFormats, args)
+	}
+
+	info, err := os.Stat(args.Path)
+	if err != nil {
+		return fmt.Errorf("Failed stat file: %s", err)
+	}
+
+	if info.IsDir() {
+		return fmt.Errorf("'%s' is a directory, use --recursive to import directories", info.Name())
+	}
+
 	fromMime := args.Mime
 	if fromMime == "" {
 		fromMime = getMimeType(args.Path)
@@ -26,32 +66,174 @@ func (self *Drive) Import(args ImportArgs) error {
 		return fmt.Errorf("Could not determine mime type of file, use --mime")
 	}
 
-	about, err := self.service.About.Get().Fields("importFormats").Do()
+	toMime, err := getImportMime(about.ImportFormats, fromMime, args.To)
 	if err != nil {
-		return fmt.Errorf("Failed to get about: %s", err)
+		return err
 	}
 
-	toMimes, ok := about.ImportFormats[fromMime]
-	if !ok || len(toMimes) == 0 {
-		return fmt.Errorf("Mime type '%s' is not supported for import", fromMime)
+	f, err := self.importFile(args.Path, toMime, args.Parents, args)
+	if err != nil {
+		return err
 	}
 
-	f, _, err := self.uploadFile(UploadArgs{
-		Out:      ioutil.Discard,
-		Progress: args.Progress,
-		Path:     args.Path,
-		Parents:  args.Parents,
-		Mime:     toMimes[0],
+	fmt.Fprintf(args.Out, "Imported %s with mime type: '%s'\n", f, toMime)
+	return nil
+}
+
+type skippedImport struct {
+	path   string
+	reason string
+}
+
+func (self *Drive) importRecursive(formats map[string][]string, args ImportArgs) error {
+	info, err := os.Stat(args.Path)
+	if err != nil {
+		return fmt.Errorf("Failed stat file: %s", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", args.Path)
+	}
+
+	var skipped []skippedImport
+	err = self.importDirectory(args.Path, args.Parents, formats, args, &skipped)
+	if err != nil {
+		return err
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(args.Out, "\n%d files were skipped:\n", len(skipped))
+		printSkippedImports(args.Out, skipped)
+	}
+
+	return nil
+}
+
+func (self *Drive) importDirectory(path string, parents []string, formats map[string][]string, args ImportArgs, skipped *[]skippedImport) error {
+	srcFile, srcFileInfo, err := openFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Close file on function exit
+	defer srcFile.Close()
+
+	fmt.Fprintf(args.Out, "Creating directory %s\n", srcFileInfo.Name())
+	dir, err := self.mkdir(MkdirArgs{
+		Out:     args.Out,
+		Name:    srcFileInfo.Name(),
+		Parents: parents,
 	})
 	if err != nil {
 		return err
 	}
 
-	fmt.Fprintf(args.Out, "Imported %s with mime type: '%s'\n", f.Id, toMimes[0])
+	// Read files from directory
+	infos, err := srcFile.Readdir(0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("Failed reading directory: %s", err)
+	}
+
+	for _, info := range infos {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		fpath := filepath.Join(path, info.Name())
+
+		if info.IsDir() {
+			err = self.importDirectory(fpath, []string{dir.Id}, formats, args, skipped)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Skip files that are not regular files
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		fromMime := getMimeType(fpath)
+		if fromMime == "" {
+			*skipped = append(*skipped, skippedImport{fpath, "unknown mime type"})
+			continue
+		}
+
+		toMime, err := getImportMime(formats, fromMime, args.To)
+		if err != nil {
+			*skipped = append(*skipped, skippedImport{fpath, err.Error()})
+			continue
+		}
+
+		id, err := self.importFile(fpath, toMime, []string{dir.Id}, args)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(args.Out, "Imported %s with mime type: '%s'\n", id, toMime)
+	}
+
 	return nil
 }
 
+func (self *Drive) importFile(path, toMime string, parents []string, args ImportArgs) (string, error) {
+	f, _, err := self.uploadFile(UploadArgs{
+		Out:         ioutil.Discard,
+		Progress:    args.Progress,
+		Path:        path,
+		Parents:     parents,
+		Mime:        toMime,
+		OcrLanguage: args.OcrLanguage,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return f.Id, nil
+}
+
+// Returns the google document mime type the file should be converted to,
+// the first available conversion is used unless a target mime is given
+func getImportMime(formats map[string][]string, fromMime, toMime string) (string, error) {
+	toMimes, ok := formats[fromMime]
+	if !ok || len(toMimes) == 0 {
+		return "", fmt.Errorf("Mime type '%s' is not supported for import", fromMime)
+	}
+
+	if toMime == "" {
+		return toMimes[0], nil
+	}
+
+	for _, m := range toMimes {
+		if m == toMime {
+			return m, nil
+		}
+	}
+
+	return "", fmt.Errorf("Mime type '%s' can not be imported as '%s', available: %s", fromMime, toMime, formatList(toMimes))
+}
+
+func printSkippedImports(out io.Writer, skipped []skippedImport) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Path\tReason")
+
+	for _, s := range skipped {
+		fmt.Fprintf(w, "%s\t%s\n", s.path, s.reason)
+	}
+
+	w.Flush()
+}
+
 func getMimeType(path string) string {
-	t := mime.TypeByExtension(filepath.Ext(path))
+	ext := filepath.Ext(path)
+	t := mime.TypeByExtension(ext)
+	if t == "" {
+		// Fall back to the known export formats as the system
+		// mime database often lacks the office formats
+		t = getFormatMimeType(strings.TrimPrefix(strings.ToLower(ext), "."))
+	}
 	return strings.Split(t, ";")[0]
 }
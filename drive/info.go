@@ -13,14 +13,31 @@ type FileInfoArgs struct {
 	SizeInBytes bool
 }
 
-func (args *FileInfoArgs) normalize(drive *Drive) {
-	finder := drive.newPathFinder()
-	args.Id = finder.SecureFileId(args.Id)
-}
-
 func (self *Drive) Info(args FileInfoArgs) error {
-	args.normalize(self)
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		// Separate file infos with a blank line
+		if i > 0 {
+			fmt.Fprintln(args.Out)
+		}
+
+		fileArgs := args
+		fileArgs.Id = id
+
+		err = self.infoOne(fileArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+func (self *Drive) infoOne(args FileInfoArgs) error {
 	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "size", "createdTime", "modifiedTime", "md5Checksum", "mimeType", "parents", "shared", "description", "webContentLink", "webViewLink").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get file: %s", err)
@@ -0,0 +1,71 @@
+package drive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"golang.org/x/net/context"
+)
+
+// Returned when a command is aborted because the context was canceled,
+// i.e. when the user presses ctrl-c
+var ErrInterrupted = errors.New("Interrupted")
+
+// Sets the context used for all requests, canceling
+// the context aborts the current operation
+func (self *Drive) SetContext(ctx context.Context) {
+	self.ctx = ctx
+}
+
+func (self *Drive) rootContext() context.Context {
+	return self.ctx
+}
+
+func (self *Drive) interrupted() bool {
+	return self.ctx.Err() != nil
+}
+
+// Keeps track of the steps of a long running command
+// to be able to tell what was done when interrupted
+type summary struct {
+	steps []*summaryStep
+}
+
+type summaryStep struct {
+	action string
+	total  int
+	done   int
+}
+
+func (self *summary) step(action string, total int) *summaryStep {
+	step := &summaryStep{action: action, total: total}
+	self.steps = append(self.steps, step)
+	return step
+}
+
+func (self *summaryStep) inc() {
+	self.done++
+}
+
+// Returns ErrInterrupted and prints the summary if the command
+// was interrupted, otherwise the given error is returned as is
+func (self *Drive) abort(out io.Writer, s *summary, err error) error {
+	if !self.interrupted() {
+		return err
+	}
+
+	fmt.Fprintln(out, "\nInterrupted, partial files have been removed")
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Step\tDone\tNot done")
+	for _, step := range s.steps {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", step.action, step.done, step.total-step.done)
+	}
+
+	w.Flush()
+	return ErrInterrupted
+}
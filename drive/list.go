@@ -5,7 +5,6 @@ import (
 	"io"
 	"text/tabwriter"
 
-	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
@@ -15,6 +14,8 @@ type ListFilesArgs struct {
 	MaxFiles    int64
 	NameWidth   int64
 	Query       string
+	Filter      QueryFilter
+	ShowQuery   bool
 	SortOrder   string
 	SkipHeader  bool
 	SizeInBytes bool
@@ -22,8 +23,18 @@ type ListFilesArgs struct {
 }
 
 func (self *Drive) List(args ListFilesArgs) (err error) {
+	query, err := self.buildQuery(args.Query, args.Filter)
+	if err != nil {
+		return err
+	}
+
+	if args.ShowQuery {
+		fmt.Fprintln(args.Out, query)
+		return nil
+	}
+
 	listArgs := listAllFilesArgs{
-		query:     args.Query,
+		query:     query,
 		fields:    []googleapi.Field{"nextPageToken", "files(id,name,md5Checksum,mimeType,size,createdTime,parents)"},
 		sortOrder: args.SortOrder,
 		maxFiles:  args.MaxFiles,
@@ -36,6 +47,10 @@ func (self *Drive) List(args ListFilesArgs) (err error) {
 	finder := self.newPathFinder()
 
 	if args.AbsPath {
+		if err := finder.PrefetchParents(files); err != nil {
+			return err
+		}
+
 		// Replace name with absolute path
 		for _, f := range files {
 			f.Name, err = finder.GetAbsPath(f)
@@ -82,7 +97,7 @@ func (self *Drive) listAllFiles(args listAllFilesArgs) ([]*drive.File, error) {
 
 	controlledStop := fmt.Errorf("Controlled stop")
 
-	err := self.service.Files.List().Q(args.query).Fields(fields...).OrderBy(args.sortOrder).PageSize(pageSize).Pages(context.TODO(), func(fl *drive.FileList) error {
+	err := self.service.Files.List().Q(args.query).Fields(fields...).OrderBy(args.sortOrder).PageSize(pageSize).Pages(self.ctx, func(fl *drive.FileList) error {
 		files = append(files, fl.Files...)
 
 		// Stop when we have all the files we need
@@ -139,6 +154,8 @@ func filetype(f *drive.File) string {
 		return "dir"
 	} else if isBinary(f) {
 		return "bin"
+	} else if isShortcut(f) {
+		return "shortcut"
 	}
 	return "doc"
 }
@@ -3,48 +3,153 @@ package drive
 import (
 	"fmt"
 	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 )
 
+// Max number of directories listed at the same time
+const MaxListWorkers = 4
+
 type ListDirectoryArgs struct {
-	Out       io.Writer
-	Id        string
-	Recursive bool
-	ShowDoc   bool
-}
+	Out           io.Writer
+	Id            string
+	Recursive     bool
+	ShowDoc       bool
+	IncludeShared bool
+	// Max depth of recursion, 0 is unlimited
+	Depth int64
+	// List the content of directories that shortcuts point to
+	FollowShortcuts bool
 
-func (args *ListDirectoryArgs) normalize(drive *Drive) {
-	finder := drive.newPathFinder()
-	args.Id = finder.SecureFileId(args.Id)
+	// Filters, only matching files are printed
+	Type          string
+	NameGlob      string
+	ModifiedAfter time.Time
 }
 
 func (self *Drive) ListDirectory(args ListDirectoryArgs) (err error) {
-	args.normalize(self)
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
 
-	printer := NewDirectoryPrinter(self, &args)
-	printer.Print(args.Id)
+	printer, err := NewDirectoryPrinter(self, &args)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		err = printer.Print(id)
+		if err != nil {
+			return err
+		}
+	}
 	return
 }
 
+// Selects the files that are printed
+type listFilter struct {
+	// file, dir, doc, shortcut or a mime type
+	typ           string
+	nameGlob      string
+	modifiedAfter time.Time
+}
+
+func newListFilter(typ, nameGlob string, modifiedAfter time.Time) (*listFilter, error) {
+	switch {
+	case typ == "", typ == "file", typ == "dir", typ == "doc", typ == "shortcut", strings.Contains(typ, "/"):
+	default:
+		return nil, fmt.Errorf("Unknown type '%s', valid types are: file, dir, doc, shortcut or a mime type", typ)
+	}
+
+	if _, err := path.Match(nameGlob, ""); err != nil {
+		return nil, fmt.Errorf("Invalid name glob '%s': %s", nameGlob, err)
+	}
+
+	return &listFilter{typ: typ, nameGlob: nameGlob, modifiedAfter: modifiedAfter}, nil
+}
+
+func (self *listFilter) match(f *drive.File) bool {
+	switch self.typ {
+	case "":
+	case "file":
+		if !isBinary(f) {
+			return false
+		}
+	case "dir":
+		if !isDir(f) {
+			return false
+		}
+	case "doc":
+		if !isDoc(f) {
+			return false
+		}
+	case "shortcut":
+		if !isShortcut(f) {
+			return false
+		}
+	default:
+		if f.MimeType != self.typ {
+			return false
+		}
+	}
+
+	if self.nameGlob != "" {
+		if ok, _ := path.Match(self.nameGlob, f.Name); !ok {
+			return false
+		}
+	}
+
+	if !self.modifiedAfter.IsZero() {
+		modified, err := time.Parse(time.RFC3339, f.ModifiedTime)
+		if err != nil || !modified.After(self.modifiedAfter) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type DirectoryPrinter struct {
 	Drive      *Drive
 	PathFinder *remotePathFinder
 	Out        io.Writer
 
 	// Options
-	Recursive bool
-	ShowDoc   bool
+	Recursive       bool
+	ShowDoc         bool
+	IncludeShared   bool
+	Depth           int64
+	FollowShortcuts bool
+	Filter          *listFilter
+
+	// Directories being printed, used to detect shortcut cycles
+	visiting map[string]bool
 }
 
-func NewDirectoryPrinter(drive *Drive, args *ListDirectoryArgs) *DirectoryPrinter {
-	return &DirectoryPrinter{
-		Drive:      drive,
-		PathFinder: drive.newPathFinder(),
-		Out:        args.Out,
-		Recursive:  args.Recursive,
-		ShowDoc:    args.ShowDoc,
+func NewDirectoryPrinter(drive *Drive, args *ListDirectoryArgs) (*DirectoryPrinter, error) {
+	filter, err := newListFilter(args.Type, args.NameGlob, args.ModifiedAfter)
+	if err != nil {
+		return nil, err
 	}
+
+	return &DirectoryPrinter{
+		Drive:           drive,
+		PathFinder:      drive.newPathFinder(),
+		Out:             args.Out,
+		Recursive:       args.Recursive,
+		ShowDoc:         args.ShowDoc || args.Type == "doc",
+		IncludeShared:   args.IncludeShared,
+		Depth:           args.Depth,
+		FollowShortcuts: args.FollowShortcuts,
+		Filter:          filter,
+		visiting:        map[string]bool{},
+	}, nil
 }
 
 func (printer *DirectoryPrinter) Print(id string) error {
@@ -52,15 +157,30 @@ func (printer *DirectoryPrinter) Print(id string) error {
 	if err != nil {
 		return err
 	}
-	if isDir(f) {
-		printer.printDirectory(f, "")
-	} else {
-		printer.printEntry(f, "")
+
+	if isShortcut(f) {
+		target, err := printer.Drive.shortcutTarget(f, defaultGetFields...)
+		if err != nil {
+			return err
+		}
+		if !isDir(target) {
+			return printer.printShortcut(f, "", target)
+		}
+		f = target
 	}
-	return nil
+
+	if !isDir(f) {
+		return printer.printEntry(f, "")
+	}
+
+	children, err := printer.Drive.listDirectories([]*drive.File{f}, printer.IncludeShared)
+	if err != nil {
+		return err
+	}
+	return printer.printDirectory(f, "", children[0], 1)
 }
 
-func (printer *DirectoryPrinter) printDirectory(file *drive.File, fullPath string) error {
+func (printer *DirectoryPrinter) printDirectory(file *drive.File, fullPath string, files []*drive.File, depth int64) error {
 
 	if len(fullPath) == 0 {
 		name, err := printer.PathFinder.GetAbsPath(file)
@@ -71,20 +191,13 @@ func (printer *DirectoryPrinter) printDirectory(file *drive.File, fullPath strin
 	}
 	fmt.Fprintf(printer.Out, "+ %v:\n", fullPath)
 
-	files, err := printer.Drive.listAllFiles(listAllFilesArgs{
-		query:     fmt.Sprintf("trashed = false and 'me' in owners and '%v' in parents", file.Id),
-		sortOrder: "folder, name",
-	})
-	if err != nil {
-		return fmt.Errorf("failed to list files: %s", err)
-	}
+	printer.visiting[file.Id] = true
+	defer delete(printer.visiting, file.Id)
 
-	type directory struct {
-		file     *drive.File
-		fullPath string
-	}
+	targets := printer.shortcutTargets(files)
 
-	var directories []directory
+	var directories []*drive.File
+	var directoryPaths []string
 	for _, f := range files {
 		if isDoc(f) && !printer.ShowDoc {
 			continue
@@ -92,15 +205,53 @@ func (printer *DirectoryPrinter) printDirectory(file *drive.File, fullPath strin
 
 		fullPath := printer.PathFinder.JoinPath(fullPath, f.Name)
 		if isDir(f) {
-			directories = append(directories, directory{f, fullPath})
+			directories = append(directories, f)
+			directoryPaths = append(directoryPaths, fullPath)
+		}
+
+		if !isShortcut(f) {
+			if printer.Filter.match(f) {
+				printer.printEntry(f, fullPath)
+			}
+			continue
+		}
+
+		target := targets[f.Id]
+		if printer.Filter.match(f) {
+			if err := printer.printShortcut(f, fullPath, target); err != nil {
+				return err
+			}
+		}
+
+		if target == nil || !isDir(target) || !printer.FollowShortcuts {
+			continue
 		}
-		printer.printEntry(f, fullPath)
+
+		if printer.visiting[target.Id] {
+			fmt.Fprintf(printer.Out, "Skipping '%s', shortcut cycle detected\n", fullPath)
+			continue
+		}
+
+		directories = append(directories, target)
+		directoryPaths = append(directoryPaths, fullPath)
+	}
+
+	if !printer.Recursive || (printer.Depth > 0 && depth >= printer.Depth) {
+		return nil
 	}
 
-	if printer.Recursive {
-		fmt.Fprint(printer.Out, "\n")
-		for _, d := range directories {
-			printer.printDirectory(d.file, d.fullPath)
+	fmt.Fprint(printer.Out, "\n")
+
+	// The subdirectories are listed concurrently, but printed in order
+	children, err := printer.Drive.listDirectories(directories, printer.IncludeShared)
+	if err != nil {
+		return err
+	}
+
+	for i, d := range directories {
+		err := printer.printDirectory(d, directoryPaths[i], children[i], depth+1)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -124,3 +275,95 @@ func (printer *DirectoryPrinter) printEntry(file *drive.File, fullPath string) e
 	fmt.Fprintf(printer.Out, "%v%v\n", fullPath, term)
 	return nil
 }
+
+// Prints the shortcut with the path of its target,
+// the target is nil if it could not be found
+func (printer *DirectoryPrinter) printShortcut(file *drive.File, fullPath string, target *drive.File) error {
+	if len(fullPath) == 0 {
+		name, err := printer.PathFinder.GetAbsPath(file)
+		if err != nil {
+			return err
+		}
+		fullPath = name
+	}
+
+	if target == nil {
+		fmt.Fprintf(printer.Out, "%v -> ?\n", fullPath)
+		return nil
+	}
+
+	targetPath, err := printer.PathFinder.GetAbsPath(target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(printer.Out, "%v -> %v\n", fullPath, targetPath)
+	return nil
+}
+
+// Returns the targets of the shortcuts among the files by shortcut id.
+// Shortcuts to files that could not be found, i.e. because they were
+// deleted or are not shared anymore, have a nil target
+func (printer *DirectoryPrinter) shortcutTargets(files []*drive.File) map[string]*drive.File {
+	var shortcuts []*drive.File
+	for _, f := range files {
+		if isShortcut(f) {
+			shortcuts = append(shortcuts, f)
+		}
+	}
+
+	resolved, errors := printer.Drive.shortcutTargets(shortcuts, defaultGetFields...)
+
+	targets := map[string]*drive.File{}
+	for i, f := range shortcuts {
+		if errors[i] == nil {
+			targets[f.Id] = resolved[i]
+		}
+	}
+	return targets
+}
+
+// Lists the children of the directories, folders first, using up to
+// MaxListWorkers concurrent requests. The results are in the same order
+// as the directories. Only files owned by the user are included unless
+// includeShared is given
+func (self *Drive) listDirectories(dirs []*drive.File, includeShared bool) ([][]*drive.File, error) {
+	children := make([][]*drive.File, len(dirs))
+	errors := make([]error, len(dirs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for n := 0; n < min(MaxListWorkers, len(dirs)); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				query := childrenQuery(dirs[i].Id)
+				if !includeShared {
+					query += " and 'me' in owners"
+				}
+
+				children[i], errors[i] = self.listAllFiles(listAllFilesArgs{
+					query:     query,
+					fields:    []googleapi.Field{"nextPageToken", "files(id,name,md5Checksum,mimeType,size,createdTime,modifiedTime,parents)"},
+					sortOrder: "folder, name",
+				})
+			}
+		}()
+	}
+
+	for i := range dirs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errors {
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list files in '%s': %s", dirs[i].Name, err)
+		}
+	}
+
+	return children, nil
+}
@@ -0,0 +1,190 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type SetMetaArgs struct {
+	Out  io.Writer
+	Id   string
+	Name string
+	// Nil leaves the description as is, empty clears it
+	Description *string
+	MimeType    string
+	Star        bool
+	Unstar      bool
+	// key=value pairs, an empty value removes the property
+	Properties    []string
+	AppProperties []string
+}
+
+// Updates the metadata of the files without uploading content. The
+// update is sent as a plain request as the vendored api client can not
+// remove properties, which requires sending null values
+func (self *Drive) SetMeta(args SetMetaArgs) error {
+	if args.Star && args.Unstar {
+		return fmt.Errorf("--star and --unstar can not be combined")
+	}
+
+	body := map[string]interface{}{}
+
+	if args.Name != "" {
+		body["name"] = args.Name
+	}
+	if args.Description != nil {
+		body["description"] = *args.Description
+	}
+	if args.MimeType != "" {
+		body["mimeType"] = args.MimeType
+	}
+	if args.Star || args.Unstar {
+		body["starred"] = args.Star
+	}
+
+	if len(args.Properties) > 0 {
+		properties, err := propertyUpdates(args.Properties)
+		if err != nil {
+			return err
+		}
+		body["properties"] = properties
+	}
+
+	if len(args.AppProperties) > 0 {
+		properties, err := propertyUpdates(args.AppProperties)
+		if err != nil {
+			return err
+		}
+		body["appProperties"] = properties
+	}
+
+	if len(body) == 0 {
+		return fmt.Errorf("Nothing to update, see the options of meta set")
+	}
+
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	// Same-named siblings break path lookups
+	if (args.Name != "" || args.MimeType != "") && len(ids) > 1 {
+		return fmt.Errorf("--name and --mime can only be used with a single file, '%s' matches %d files", args.Id, len(ids))
+	}
+
+	for _, id := range ids {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		result := self.executeRequest(&batchRequest{
+			method: "PATCH",
+			path:   "files/" + url.PathEscape(id),
+			query:  url.Values{"fields": {"id,name"}},
+			body:   body,
+		})
+
+		updated := &drive.File{}
+		if err := result.decode(updated); err != nil {
+			return fmt.Errorf("Failed to update metadata of %s: %s", id, err)
+		}
+
+		fmt.Fprintf(args.Out, "Updated metadata of '%s'\n", updated.Name)
+	}
+
+	return nil
+}
+
+// Returns the property values to send, nil values remove the property
+func propertyUpdates(pairs []string) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+
+	for _, pair := range pairs {
+		key, value, err := parseKeyValue(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		if value == "" {
+			properties[key] = nil
+		} else {
+			properties[key] = value
+		}
+	}
+
+	return properties, nil
+}
+
+// Splits key=value, the value may be empty
+func parseKeyValue(pair string) (string, string, error) {
+	pos := strings.Index(pair, "=")
+	if pos < 1 {
+		return "", "", fmt.Errorf("Invalid property '%s', use key=value", pair)
+	}
+	return pair[:pos], pair[pos+1:], nil
+}
+
+type GetMetaArgs struct {
+	Out io.Writer
+	Id  string
+}
+
+func (self *Drive) GetMeta(args GetMetaArgs) error {
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range ids {
+		// Separate files with a blank line
+		if i > 0 {
+			fmt.Fprintln(args.Out)
+		}
+
+		f, err := self.service.Files.Get(id).Fields("id", "name", "mimeType", "description", "starred", "properties", "appProperties").Do()
+		if err != nil {
+			return fmt.Errorf("Failed to get file: %s", err)
+		}
+
+		items := []kv{
+			kv{"Id", f.Id},
+			kv{"Name", f.Name},
+			kv{"Mime", f.MimeType},
+			kv{"Description", f.Description},
+			kv{"Starred", formatBool(f.Starred)},
+		}
+
+		for _, item := range items {
+			if item.value != "" {
+				fmt.Fprintf(args.Out, "%s: %s\n", item.key, item.value)
+			}
+		}
+
+		printProperties(args.Out, "Properties", f.Properties)
+		printProperties(args.Out, "AppProperties", f.AppProperties)
+	}
+
+	return nil
+}
+
+func printProperties(out io.Writer, title string, properties map[string]string) {
+	if len(properties) == 0 {
+		return
+	}
+
+	var keys []string
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(out, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(out, "  %s=%s\n", key, properties[key])
+	}
+}
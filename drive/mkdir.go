@@ -16,20 +16,20 @@ type MkdirArgs struct {
 	Parents     []string
 }
 
-func (args *MkdirArgs) normalize(drive *Drive) {
-	if len(args.Parents) > 0 {
-		var ids []string
-		finder := drive.newPathFinder()
-		for _, parent := range args.Parents {
-			id := finder.SecureFileId(parent)
-			ids = append(ids, id)
-		}
-		args.Parents = ids
+func (args *MkdirArgs) normalize(drive *Drive) error {
+	ids, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
 	}
+
+	args.Parents = ids
+	return nil
 }
 
 func (self *Drive) Mkdir(args MkdirArgs) error {
-	args.normalize(self)
+	if err := args.normalize(self); err != nil {
+		return err
+	}
 
 	f, err := self.mkdir(args)
 	if err != nil {
@@ -0,0 +1,96 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type MoveArgs struct {
+	Out      io.Writer
+	Id       string
+	ParentId string
+}
+
+func (self *Drive) Move(args MoveArgs) error {
+	ids, err := self.resolveFileIds(args.Id)
+	if err != nil {
+		return err
+	}
+
+	parentId, err := self.resolveFileId(args.ParentId)
+	if err != nil {
+		return err
+	}
+
+	parent, err := self.service.Files.Get(parentId).Fields("id", "name", "mimeType").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get parent: %s", err)
+	}
+
+	if !isDir(parent) {
+		return fmt.Errorf("'%s' is not a directory", parent.Name)
+	}
+
+	if len(ids) > 1 {
+		return self.moveBatch(ids, parent, args)
+	}
+
+	f, err := self.service.Files.Get(ids[0]).Fields("id", "name", "parents").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	_, err = self.service.Files.Update(f.Id, &drive.File{}).AddParents(parent.Id).RemoveParents(strings.Join(f.Parents, ",")).Fields("id").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to move file: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Moved '%s' to '%s'\n", f.Name, parent.Name)
+	return nil
+}
+
+// Moves multiple files using batch requests
+func (self *Drive) moveBatch(ids []string, parent *drive.File, args MoveArgs) error {
+	files, errors := self.batchGetFiles(ids, "id", "name", "parents")
+
+	var moved []*drive.File
+	var failed int
+
+	for i, f := range files {
+		if errors[i] != nil {
+			fmt.Fprintf(args.Out, "Failed to get file '%s': %s\n", ids[i], errors[i])
+			failed++
+			continue
+		}
+		moved = append(moved, f)
+	}
+
+	var requests []*batchRequest
+	for _, f := range moved {
+		query := fieldsQuery("id")
+		query.Set("addParents", parent.Id)
+		query.Set("removeParents", strings.Join(f.Parents, ","))
+
+		requests = append(requests, &batchRequest{
+			method: "PATCH",
+			path:   "files/" + url.PathEscape(f.Id),
+			query:  query,
+			body:   struct{}{},
+		})
+	}
+
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(args.Out, "Failed to move '%s': %s\n", moved[i].Name, result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(args.Out, "Moved '%s' to '%s'\n", moved[i].Name, parent.Name)
+	}
+
+	return batchError("move", failed, len(ids))
+}
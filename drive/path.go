@@ -2,9 +2,9 @@ package drive
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
-	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
@@ -23,6 +23,8 @@ func (self *Drive) newPathFinder() *remotePathFinder {
 	return &remotePathFinder{
 		service: self.service.Files,
 		caches:  make(map[string]*fileEntry),
+		queries: make(map[string][]*drive.File),
+		drive:   self,
 	}
 }
 
@@ -32,11 +34,25 @@ type fileEntry struct {
 }
 
 type remotePathFinder struct {
-	service *drive.FilesService
-	caches  map[string]*fileEntry // id -> entry
+	service  *drive.FilesService
+	caches   map[string]*fileEntry    // id -> entry
+	queries  map[string][]*drive.File // query -> prefetched result
+	drive    *Drive
+	metadata *metadataCache // on-disk cache, nil if disabled
+	loaded   bool
+}
+
+// Returns the on-disk cache, which is loaded on first use
+func (self *remotePathFinder) metadataCache() *metadataCache {
+	if !self.loaded {
+		self.metadata = self.drive.metadataCache()
+		self.loaded = true
+	}
+	return self.metadata
 }
 
 func (self *remotePathFinder) GetAbsPath(f *drive.File) (string, error) {
+	defer self.persist()
 
 	if len(f.Parents) == 0 {
 		return RemotePathSep, nil
@@ -85,9 +101,8 @@ func (self *remotePathFinder) JoinPath(pathes ...string) string {
 }
 
 func (self *remotePathFinder) GetFile(id string) (*drive.File, error) {
-	// Check cache
-	if entry, ok := self.caches[id]; ok {
-		return entry.file, nil
+	if f, ok := self.cachedFile(id); ok {
+		return f, nil
 	}
 
 	// Fetch file from drive
@@ -96,13 +111,99 @@ func (self *remotePathFinder) GetFile(id string) (*drive.File, error) {
 		return nil, fmt.Errorf("Failed to get file: %s", err)
 	}
 
+	self.saveFetched(id, f)
+	return f, nil
+}
+
+// Returns the file from the in-memory or on-disk cache
+func (self *remotePathFinder) cachedFile(id string) (*drive.File, bool) {
+	if entry, ok := self.caches[id]; ok {
+		return entry.file, true
+	}
+
+	if self.metadataCache() != nil {
+		if f, ok := self.metadataCache().get(self.metadataId(id)); ok {
+			self.saveCache(f, "")
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+func (self *remotePathFinder) saveFetched(id string, f *drive.File) {
 	self.saveCache(f, "")
 
-	return f, nil
+	if self.metadataCache() != nil {
+		self.metadataCache().add(f)
+		if id == "root" {
+			self.metadataCache().setRootId(f.Id)
+		}
+	}
+}
+
+// Fetches the parent directories of the given files using batch
+// requests, one level at a time, so that GetAbsPath can be answered
+// from the cache instead of doing a request per directory
+func (self *remotePathFinder) PrefetchParents(files []*drive.File) error {
+	defer self.persist()
+
+	visited := map[string]bool{}
+
+	for len(files) > 0 {
+		var next []*drive.File
+		var missing []string
+
+		for _, f := range files {
+			if len(f.Parents) == 0 || visited[f.Parents[0]] {
+				continue
+			}
+
+			parentId := f.Parents[0]
+			visited[parentId] = true
+
+			if parent, ok := self.cachedFile(parentId); ok {
+				next = append(next, parent)
+			} else {
+				missing = append(missing, parentId)
+			}
+		}
+
+		if len(missing) > 0 {
+			fetched, errors := self.drive.batchGetFiles(missing, defaultGetFields...)
+			for i, f := range fetched {
+				if errors[i] != nil {
+					return fmt.Errorf("Failed to get file: %s", errors[i])
+				}
+				self.saveFetched(missing[i], f)
+				next = append(next, f)
+			}
+		}
+
+		files = next
+	}
+
+	return nil
+}
+
+// Translates the root alias to the actual root id known by the on-disk cache
+func (self *remotePathFinder) metadataId(id string) string {
+	if id == "root" {
+		if rootId := self.metadataCache().rootId(); rootId != "" {
+			return rootId
+		}
+	}
+	return id
+}
+
+func (self *remotePathFinder) persist() {
+	if self.metadataCache() != nil {
+		self.metadataCache().persist()
+	}
 }
 
 func (self *remotePathFinder) GetFileId(absPath string) (string, error) {
-	if !strings.HasPrefix(absPath, "/") {
+	if !isRemotePath(absPath) {
 		return "", fmt.Errorf("'%s' is not absolute path", absPath)
 	}
 
@@ -118,64 +219,304 @@ func (self *remotePathFinder) GetFileId(absPath string) (string, error) {
 		}
 	}
 
-	pathes := strings.Split(absPath[1:], "/")
-	var parent string = "root"
-	var f *drive.File
-	for _, path := range pathes {
-		entry := self.queryEntryByName(path, parent)
-		if entry == nil {
-			return "", fmt.Errorf("path not found: '%v'", absPath)
+	files, err := self.FindFiles(absPath)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) > 1 {
+		return "", fmt.Errorf("'%s' matches %d files, expected exactly one", absPath, len(files))
+	}
+
+	self.saveCache(files[0], absPath)
+
+	return files[0].Id, nil
+}
+
+// Returns the files matching the given absolute path. Each path
+// segment may be a shell pattern (see path.Match), which can make the
+// path match multiple files, unless a file has exactly the name of the
+// segment, see queryEntriesByPattern. Segments without patterns are required to
+// match a single file, as drive allows siblings with the same name.
+// Shortcuts are followed, except in the last segment so that commands
+// like delete act on the shortcut itself
+func (self *remotePathFinder) FindFiles(absPath string) ([]*drive.File, error) {
+	defer self.persist()
+
+	if !isRemotePath(absPath) {
+		return nil, fmt.Errorf("'%s' is not absolute path", absPath)
+	}
+
+	absPath = strings.TrimRight(absPath, "/")
+	if absPath == "" {
+		f, err := self.GetFile("root")
+		if err != nil {
+			return nil, err
 		}
-		f = entry
-		parent = f.Id
+		return []*drive.File{f}, nil
 	}
 
-	self.saveCache(f, absPath)
+	parents := []string{"root"}
+	var files []*drive.File
+
+	segments := strings.Split(absPath[1:], "/")
+	for i, name := range segments {
+		files = nil
+
+		if err := self.prefetchEntries(name, parents); err != nil {
+			return nil, err
+		}
+
+		for _, parentId := range parents {
+			var entries []*drive.File
+			var err error
+
+			if hasGlobMeta(name) {
+				entries, err = self.queryEntriesByPattern(name, parentId)
+			} else {
+				entries, err = self.queryEntriesByName(name, parentId)
+				if err == nil && len(entries) > 1 {
+					err = fmt.Errorf("Ambiguous path '%s', found %d files named '%s' in the same directory: %s", absPath, len(entries), name, formatList(fileIds(entries)))
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, entries...)
+		}
+
+		if len(files) == 0 {
+			return nil, fmt.Errorf("path not found: '%v'", absPath)
+		}
+
+		if i < len(segments)-1 {
+			var err error
+			files, err = self.followShortcuts(files)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		parents = fileIds(files)
+	}
 
-	return parent, nil
+	return files, nil
 }
 
-func (self *remotePathFinder) SecureFileId(expr string) string {
-	if strings.Contains(expr, "/") {
-		id, err := self.GetFileId(expr)
-		if err == nil {
-			return string(id)
+// Replaces the shortcuts with their targets
+func (self *remotePathFinder) followShortcuts(files []*drive.File) ([]*drive.File, error) {
+	var shortcuts []*drive.File
+	var indexes []int
+	for i, f := range files {
+		if isShortcut(f) {
+			shortcuts = append(shortcuts, f)
+			indexes = append(indexes, i)
 		}
 	}
-	return expr
+
+	if len(shortcuts) == 0 {
+		return files, nil
+	}
+
+	targets, errors := self.drive.shortcutTargets(shortcuts, defaultGetFields...)
+
+	followed := make([]*drive.File, len(files))
+	copy(followed, files)
+
+	for j, i := range indexes {
+		if errors[j] != nil {
+			return nil, errors[j]
+		}
+		self.saveFetched(targets[j].Id, targets[j])
+		followed[i] = targets[j]
+	}
+
+	return followed, nil
 }
 
-func (self *remotePathFinder) queryEntryByName(name string, parentId string) *drive.File {
+// Returns the file id of the given file id or absolute path
+func (self *remotePathFinder) ResolveFileId(expr string) (string, error) {
+	if !isRemotePath(expr) {
+		return expr, nil
+	}
 
-	// Check cache
-	for _, entry := range self.caches {
-		if entry.file.Name == name && entry.file.Parents[0] == parentId {
-			return entry.file
+	if !hasGlobMeta(expr) {
+		return self.GetFileId(expr)
+	}
+
+	files, err := self.FindFiles(expr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) > 1 {
+		return "", fmt.Errorf("'%s' matches %d files, this command only supports a single file", expr, len(files))
+	}
+
+	return files[0].Id, nil
+}
+
+// Returns the file ids of the given file id, absolute path or path pattern
+func (self *remotePathFinder) ResolveFileIds(expr string) ([]string, error) {
+	if !isRemotePath(expr) {
+		return []string{expr}, nil
+	}
+
+	files, err := self.FindFiles(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileIds(files), nil
+}
+
+func (self *remotePathFinder) queryEntriesByName(name string, parentId string) ([]*drive.File, error) {
+	// Use the children known by the on-disk cache
+	if self.metadataCache() != nil {
+		children, err := self.listChildren(parentId)
+		if err != nil {
+			return nil, err
 		}
+
+		var files []*drive.File
+		for _, f := range children {
+			if f.Name == name {
+				files = append(files, f)
+			}
+		}
+		return files, nil
+	}
+
+	return self.queryEntries(nameQuery(name, parentId))
+}
+
+// Returns the children matching the pattern. Names containing pattern
+// characters, like 'Report [draft].pdf', are matched literally when
+// a file with exactly that name exists
+func (self *remotePathFinder) queryEntriesByPattern(pattern string, parentId string) ([]*drive.File, error) {
+	// Names that are not valid patterns can only match literally
+	if _, err := path.Match(pattern, ""); err != nil {
+		return self.queryEntriesByName(pattern, parentId)
 	}
 
-	conditions := []string{
-		"trashed = false",
-		fmt.Sprintf("name = '%v'", name),
-		fmt.Sprintf("'%v' in parents", parentId),
+	children, err := self.listChildren(parentId)
+	if err != nil {
+		return nil, err
 	}
-	query := strings.Join(conditions, " and ")
 
 	var files []*drive.File
-	self.service.List().Q(query).Fields(defaultQueryFields...).Pages(context.TODO(), func(fl *drive.FileList) error {
-		files = append(files, fl.Files...)
+	for _, f := range children {
+		if f.Name == pattern {
+			files = append(files, f)
+		}
+	}
+	if len(files) > 0 {
+		return files, nil
+	}
+
+	for _, f := range children {
+		if ok, _ := path.Match(pattern, f.Name); ok {
+			files = append(files, f)
+		}
+	}
+
+	return files, nil
+}
+
+func (self *remotePathFinder) listChildren(parentId string) ([]*drive.File, error) {
+	if self.metadataCache() == nil {
+		return self.queryEntries(childrenQuery(parentId))
+	}
+
+	// Make sure we know the actual id of the root directory
+	if parentId == "root" && self.metadataCache().rootId() == "" {
+		if _, err := self.GetFile("root"); err != nil {
+			return nil, err
+		}
+	}
+	parentId = self.metadataId(parentId)
+
+	if files, ok := self.metadataCache().children(parentId); ok {
+		for _, f := range files {
+			self.saveCache(f, "")
+		}
+		return files, nil
+	}
+
+	files, err := self.queryEntries(childrenQuery(parentId))
+	if err != nil {
+		return nil, err
+	}
+
+	self.metadataCache().setChildren(parentId, files)
+	return files, nil
+}
+
+// Runs the queries needed to look up the given path segment in each of the
+// parent directories as batch requests. The results are stored by query
+// and later picked up by queryEntries
+func (self *remotePathFinder) prefetchEntries(name string, parentIds []string) error {
+	if len(parentIds) < 2 {
 		return nil
-	})
+	}
 
-	if len(files) == 0 {
+	var queries []string
+	for _, parentId := range parentIds {
+		if !hasGlobMeta(name) && self.metadataCache() == nil {
+			queries = append(queries, nameQuery(name, parentId))
+			continue
+		}
+
+		// Children are already known by the on-disk cache
+		if self.metadataCache() != nil {
+			if _, ok := self.metadataCache().children(self.metadataId(parentId)); ok {
+				continue
+			}
+		}
+		queries = append(queries, childrenQuery(parentId))
+	}
+
+	if len(queries) < 2 {
 		return nil
 	}
 
+	results, err := self.drive.batchListFiles(queries, defaultQueryFields...)
+	if err != nil {
+		return fmt.Errorf("Failed to list files: %s", err)
+	}
+
+	for i, query := range queries {
+		self.queries[query] = results[i]
+	}
+
+	return nil
+}
+
+func (self *remotePathFinder) queryEntries(query string) ([]*drive.File, error) {
+	// Use the result of a batch prefetch if available
+	if files, ok := self.queries[query]; ok {
+		delete(self.queries, query)
+		for _, f := range files {
+			self.saveCache(f, "")
+		}
+		return files, nil
+	}
+
+	var files []*drive.File
+	err := self.service.List().Q(query).Fields(defaultQueryFields...).Pages(self.drive.ctx, func(fl *drive.FileList) error {
+		files = append(files, fl.Files...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list files: %s", err)
+	}
+
 	for _, f := range files {
 		self.saveCache(f, "")
 	}
 
-	return files[0]
+	return files, nil
 }
 
 func (self *remotePathFinder) saveCache(f *drive.File, absPath string) {
@@ -185,8 +526,59 @@ func (self *remotePathFinder) saveCache(f *drive.File, absPath string) {
 	}
 }
 
+func (self *Drive) resolveFileId(expr string) (string, error) {
+	return self.newPathFinder().ResolveFileId(expr)
+}
+
+func (self *Drive) resolveFileIds(expr string) ([]string, error) {
+	return self.newPathFinder().ResolveFileIds(expr)
+}
+
+func (self *Drive) resolveFileIdList(exprs []string) ([]string, error) {
+	var ids []string
+	finder := self.newPathFinder()
+	for _, expr := range exprs {
+		id, err := finder.ResolveFileId(expr)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func nameQuery(name, parentId string) string {
+	return fmt.Sprintf("trashed = false and name = '%s' and '%s' in parents", escapeQueryValue(name), parentId)
+}
+
+func childrenQuery(parentId string) string {
+	return fmt.Sprintf("trashed = false and '%s' in parents", parentId)
+}
+
+func isRemotePath(expr string) bool {
+	return strings.HasPrefix(expr, RemotePathSep)
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// Escapes a string value for use in a drive query
+func escapeQueryValue(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return strings.Replace(s, `'`, `\'`, -1)
+}
+
+func fileIds(files []*drive.File) []string {
+	var ids []string
+	for _, f := range files {
+		ids = append(ids, f.Id)
+	}
+	return ids
+}
+
 func isDoc(f *drive.File) bool {
-	if isDir(f) {
+	if isDir(f) || isShortcut(f) {
 		return false
 	}
 	if isBinary(f) {
@@ -0,0 +1,106 @@
+package drive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Structured filters that are compiled into a drive query,
+// see https://developers.google.com/drive/search-parameters
+type QueryFilter struct {
+	// Terms to search for in the content and metadata
+	FullText     string
+	NameContains string
+	MimeType     string
+	// Path or id of the parent directory
+	In            string
+	ModifiedAfter time.Time
+	Owner         string
+	SharedWithMe  bool
+	Starred       bool
+	// Only trashed files. Otherwise only untrashed files,
+	// unless a raw query is given which may select either
+	Trashed bool
+	// key=value pairs
+	AppProperties []string
+}
+
+func (self QueryFilter) IsEmpty() bool {
+	return self.FullText == "" &&
+		self.NameContains == "" &&
+		self.MimeType == "" &&
+		self.In == "" &&
+		self.ModifiedAfter.IsZero() &&
+		self.Owner == "" &&
+		!self.SharedWithMe &&
+		!self.Starred &&
+		!self.Trashed &&
+		len(self.AppProperties) == 0
+}
+
+// Returns the raw query combined with the filters. The raw query is
+// returned as is if no filters are given
+func (self *Drive) buildQuery(raw string, filter QueryFilter) (string, error) {
+	if filter.IsEmpty() {
+		return raw, nil
+	}
+
+	var terms []string
+	if raw != "" {
+		terms = append(terms, fmt.Sprintf("(%s)", raw))
+	}
+
+	if filter.FullText != "" {
+		terms = append(terms, fmt.Sprintf("fullText contains '%s'", escapeQueryValue(filter.FullText)))
+	}
+
+	if filter.NameContains != "" {
+		terms = append(terms, fmt.Sprintf("name contains '%s'", escapeQueryValue(filter.NameContains)))
+	}
+
+	if filter.MimeType != "" {
+		terms = append(terms, fmt.Sprintf("mimeType = '%s'", escapeQueryValue(filter.MimeType)))
+	}
+
+	if filter.In != "" {
+		id, err := self.resolveFileId(filter.In)
+		if err != nil {
+			return "", err
+		}
+		terms = append(terms, fmt.Sprintf("'%s' in parents", escapeQueryValue(id)))
+	}
+
+	if !filter.ModifiedAfter.IsZero() {
+		terms = append(terms, fmt.Sprintf("modifiedTime > '%s'", filter.ModifiedAfter.UTC().Format(time.RFC3339)))
+	}
+
+	if filter.Owner != "" {
+		terms = append(terms, fmt.Sprintf("'%s' in owners", escapeQueryValue(filter.Owner)))
+	}
+
+	if filter.SharedWithMe {
+		terms = append(terms, "sharedWithMe = true")
+	}
+
+	if filter.Starred {
+		terms = append(terms, "starred = true")
+	}
+
+	if filter.Trashed {
+		terms = append(terms, "trashed = true")
+	} else if raw == "" {
+		terms = append(terms, "trashed = false")
+	}
+
+	for _, property := range filter.AppProperties {
+		key, value, err := parseKeyValue(property)
+		if err != nil {
+			return "", err
+		}
+
+		terms = append(terms, fmt.Sprintf("appProperties has { key='%s' and value='%s' }", escapeQueryValue(key), escapeQueryValue(value)))
+	}
+
+	return strings.Join(terms, " and "), nil
+}
@@ -0,0 +1,347 @@
+package drive
+
+import (
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+	"io"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+type RestoreArgs struct {
+	Out       io.Writer
+	Progress  io.Writer
+	Id        string
+	AsOf      time.Time
+	Path      string
+	Force     bool
+	Remote    bool
+	DryRun    bool
+	Format    string
+	ChunkSize int64
+	Timeout   time.Duration
+
+	// Google document mime type -> export mime type
+	exportMimes map[string]string
+}
+
+func (args *RestoreArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+type restoreStats struct {
+	restored int
+	skipped  int
+	failed   int
+}
+
+// Downloads the revision of every file in the directory tree that was current
+// at the given time, trashed files are included. Google documents are exported.
+// With Remote the revisions are also uploaded as the new head revision and
+// trashed files are restored
+func (self *Drive) Restore(args RestoreArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	exportMimes, err := getExportFormatFamily(args.Format)
+	if err != nil {
+		return err
+	}
+	args.exportMimes = exportMimes
+
+	f, err := self.service.Files.Get(args.Id).Fields("id", "name", "mimeType", "createdTime", "trashed").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	if !isDir(f) {
+		return fmt.Errorf("'%s' is not a directory", f.Name)
+	}
+
+	if args.DryRun {
+		fmt.Fprintf(args.Out, "Showing what would be restored as of %s\n", args.AsOf.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	stats := &restoreStats{}
+	err = self.restoreDirectory(f, f.Name, stats, args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Restored %d files, skipped %d files, failed %d files\n", stats.restored, stats.skipped, stats.failed)
+	return batchError("restore", stats.failed, stats.restored+stats.failed)
+}
+
+func (self *Drive) restoreDirectory(parent *drive.File, relPath string, stats *restoreStats, args RestoreArgs) error {
+	if parent.Trashed && args.Remote && !args.DryRun {
+		if err := self.untrash(parent); err != nil {
+			return err
+		}
+	}
+
+	listArgs := listAllFilesArgs{
+		// Trashed files are included on purpose
+		query:  fmt.Sprintf("'%s' in parents", parent.Id),
+		fields: []googleapi.Field{"nextPageToken", "files(id,name,mimeType,createdTime,trashed)"},
+	}
+	files, err := self.listAllFiles(listArgs)
+	if err != nil {
+		return fmt.Errorf("Failed listing files: %s", err)
+	}
+
+	for _, f := range files {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		path := filepath.Join(relPath, f.Name)
+
+		// Skip files that did not exist at the given time
+		created, err := time.Parse(time.RFC3339, f.CreatedTime)
+		if err == nil && created.After(args.AsOf) {
+			continue
+		}
+
+		if isDir(f) {
+			err = self.restoreDirectory(f, path, stats, args)
+		} else {
+			err = self.restoreFile(f, path, stats, args)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *Drive) restoreFile(f *drive.File, relPath string, stats *restoreStats, args RestoreArgs) error {
+	if isShortcut(f) {
+		fmt.Fprintf(args.Out, "Skipping '%s', shortcuts do not have revisions\n", relPath)
+		stats.skipped++
+		return nil
+	}
+
+	if isDoc(f) {
+		return self.restoreDoc(f, relPath, stats, args)
+	}
+
+	revisions, err := self.listAllRevisions(f.Id, "id,mimeType,modifiedTime")
+	if err != nil {
+		return err
+	}
+
+	rev, isHead := revisionAsOf(revisions, args.AsOf)
+	if rev == nil {
+		fmt.Fprintf(args.Out, "Skipping '%s', no revision as of the given time\n", relPath)
+		stats.skipped++
+		return nil
+	}
+
+	fpath := filepath.Join(args.Path, relPath)
+	fmt.Fprintf(args.Out, "Restoring %s -> %s (revision %s, %s)\n", relPath, fpath, rev.Id, formatDatetime(rev.ModifiedTime))
+
+	if args.DryRun {
+		stats.restored++
+		return nil
+	}
+
+	_, _, err = self.saveRevision(f.Id, rev.Id, args.Timeout, saveFileArgs{
+		out:      args.Out,
+		fpath:    fpath,
+		force:    args.Force,
+		progress: args.Progress,
+	})
+	if err != nil {
+		return err
+	}
+
+	if args.Remote {
+		if f.Trashed {
+			if err := self.untrash(f); err != nil {
+				return err
+			}
+		}
+
+		// The head revision is already the current content
+		if !isHead {
+			err = self.uploadRevision(f, rev, fpath, RestoreRevisionArgs{
+				Out:       args.Out,
+				Progress:  args.Progress,
+				ChunkSize: args.ChunkSize,
+				Timeout:   args.Timeout,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	stats.restored++
+	return nil
+}
+
+func (self *Drive) untrash(f *drive.File) error {
+	update := &drive.File{
+		Trashed: false,
+		// Trashed is omitted from the request when false
+		ForceSendFields: []string{"Trashed"},
+	}
+
+	_, err := self.service.Files.Update(f.Id, update).Fields("id").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to restore '%s' from trash: %s", f.Name, err)
+	}
+	return nil
+}
+
+// Returns the revision that was current at the given time, the
+// second return value is true if it is also the head revision
+func revisionAsOf(revisions []*drive.Revision, asOf time.Time) (*drive.Revision, bool) {
+	var current *drive.Revision
+	var latest time.Time
+
+	for _, rev := range revisions {
+		modified, err := time.Parse(time.RFC3339, rev.ModifiedTime)
+		if err != nil || modified.After(asOf) {
+			continue
+		}
+
+		if current == nil || !modified.Before(latest) {
+			current = rev
+			latest = modified
+		}
+	}
+
+	if current == nil {
+		return nil, false
+	}
+
+	return current, current == revisions[len(revisions)-1]
+}
+
+// Exports the revision of the google document that was current at the given
+// time. Exported revisions can not be uploaded as a new head revision, so
+// with Remote only the head revision can be restored
+func (self *Drive) restoreDoc(f *drive.File, relPath string, stats *restoreStats, args RestoreArgs) error {
+	exportMime, err := getExportMime(args.exportMimes[f.MimeType], f.MimeType)
+	if err != nil {
+		fmt.Fprintf(args.Out, "Failed to restore '%s': %s\n", relPath, err)
+		stats.failed++
+		return nil
+	}
+
+	revisions, err := self.listExportableRevisions(f.Id)
+	if err != nil {
+		return err
+	}
+
+	var revs []*drive.Revision
+	for _, r := range revisions {
+		revs = append(revs, &r.Revision)
+	}
+
+	rev, isHead := revisionAsOf(revs, args.AsOf)
+	if rev == nil {
+		fmt.Fprintf(args.Out, "Skipping '%s', no revision as of the given time\n", relPath)
+		stats.skipped++
+		return nil
+	}
+
+	var link string
+	for _, r := range revisions {
+		if r.Id == rev.Id {
+			link = r.ExportLinks[exportMime]
+		}
+	}
+
+	if link == "" {
+		fmt.Fprintf(args.Out, "Failed to restore '%s', revision %s can not be exported as '%s'\n", relPath, rev.Id, exportMime)
+		stats.failed++
+		return nil
+	}
+
+	if args.Remote && !isHead {
+		fmt.Fprintf(args.Out, "Failed to restore '%s', revision %s is not the head revision and exported documents can not be uploaded as a revision\n", relPath, rev.Id)
+		stats.failed++
+		return nil
+	}
+
+	fpath := filepath.Join(args.Path, getExportFilename(relPath, exportMime))
+	fmt.Fprintf(args.Out, "Restoring %s -> %s (revision %s, %s)\n", relPath, fpath, rev.Id, formatDatetime(rev.ModifiedTime))
+
+	if args.DryRun {
+		stats.restored++
+		return nil
+	}
+
+	_, _, err = self.saveRevisionExport(link, args.Timeout, saveFileArgs{
+		out:      args.Out,
+		fpath:    fpath,
+		force:    args.Force,
+		progress: args.Progress,
+	})
+	if err != nil {
+		return err
+	}
+
+	if args.Remote && f.Trashed {
+		if err := self.untrash(f); err != nil {
+			return err
+		}
+	}
+
+	stats.restored++
+	return nil
+}
+
+// Revision with the export links of google documents,
+// which are not known to the vendored api client
+type exportableRevision struct {
+	drive.Revision
+	ExportLinks map[string]string `json:"exportLinks"`
+}
+
+func (self *Drive) listExportableRevisions(fileId string) ([]*exportableRevision, error) {
+	var revisions []*exportableRevision
+	pageToken := ""
+
+	for {
+		query := url.Values{
+			"fields":   {"nextPageToken,revisions(id,mimeType,modifiedTime,exportLinks)"},
+			"pageSize": {"1000"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		result := self.executeRequest(&batchRequest{
+			method: "GET",
+			path:   "files/" + url.PathEscape(fileId) + "/revisions",
+			query:  query,
+		})
+
+		var list struct {
+			NextPageToken string                `json:"nextPageToken"`
+			Revisions     []*exportableRevision `json:"revisions"`
+		}
+		if err := result.decode(&list); err != nil {
+			return nil, fmt.Errorf("Failed listing revisions: %s", err)
+		}
+
+		revisions = append(revisions, list.Revisions...)
+		if list.NextPageToken == "" {
+			return revisions, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
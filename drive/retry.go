@@ -0,0 +1,238 @@
+package drive
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/api/googleapi"
+)
+
+const DefaultMaxRetries = 5
+
+// Upper limit of the backoff delay between retries
+const MaxRetryDelay = 64 * time.Second
+
+type retryPolicy struct {
+	maxRetries int
+}
+
+// Sets the number of times a failed request is retried
+func (self *Drive) SetMaxRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	self.retries.maxRetries = n
+}
+
+// Returns a copy of the given client which retries
+// requests according to the given policy
+func newRetryClient(client *http.Client, policy *retryPolicy, rootContext func() context.Context) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &http.Client{
+		Transport:     &retryTransport{base: base, policy: policy, rootContext: rootContext},
+		CheckRedirect: client.CheckRedirect,
+		Jar:           client.Jar,
+		Timeout:       client.Timeout,
+	}
+}
+
+// Http transport that retries requests that fail with a backend or rate
+// limit error. Requests with a body that can not be replayed, i.e. media
+// uploads, creations, see isRetryableRequest, and requests made by
+// Drive.retry are passed through as is.
+// Requests without a context of their own get the root context
+type retryTransport struct {
+	base        http.RoundTripper
+	policy      *retryPolicy
+	rootContext func() context.Context
+}
+
+func (self *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	canReplay := (req.Body == nil || req.GetBody != nil) &&
+		isRetryableRequest(req.Method, req.URL.Path, req.URL.Query()) &&
+		!isRetriedByCaller(req.Context())
+
+	if req.Context().Done() == nil {
+		req = req.WithContext(self.rootContext())
+	}
+
+	for try := 0; ; try++ {
+		res, err := self.base.RoundTrip(req)
+		if err != nil || !canReplay || try >= self.policy.maxRetries {
+			return res, err
+		}
+
+		retryErr := responseError(res)
+		if !isBackendOrRateLimitError(retryErr) {
+			return res, nil
+		}
+		res.Body.Close()
+
+		select {
+		case <-time.After(retryDelay(retryErr, try)):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.WithContext(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// Returns the api error of the given response, nil on success. The
+// body is buffered so that it still can be read by the caller
+func responseError(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	apiErr := googleapi.CheckResponse(&http.Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	})
+
+	// Keep the headers to be able to honor Retry-After
+	if ae, ok := apiErr.(*googleapi.Error); ok {
+		ae.Header = res.Header
+	}
+	return apiErr
+}
+
+// Returns true if the request can be resent after a failed attempt. A failed
+// attempt may still have been carried out, so POST requests, which create
+// files, shortcuts, comments and replies, are only resent when that can not
+// create duplicates: granting a permission again has no effect, a resumable
+// upload session only creates the file with its last chunk and batches are
+// marked by the caller when they contain creations
+func isRetryableRequest(method, path string, query url.Values) bool {
+	if method != "POST" {
+		return true
+	}
+
+	switch {
+	case strings.HasSuffix(path, "/permissions"):
+		return true
+	case strings.HasPrefix(path, "/batch/"):
+		return true
+	case query.Get("uploadType") == "resumable":
+		return true
+	}
+	return false
+}
+
+type retriedByCallerKey struct{}
+
+// Marks the requests made with the context as retried by the caller
+func withCallerRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retriedByCallerKey{}, true)
+}
+
+func isRetriedByCaller(ctx context.Context) bool {
+	retried, _ := ctx.Value(retriedByCallerKey{}).(bool)
+	return retried
+}
+
+// Calls the given function until it succeeds, fails with an error that is not
+// worth retrying or the max number of retries is reached. Only used for body
+// transfers that have to be restarted as a whole, like media uploads which
+// have to reopen the file and downloads interrupted while reading the body.
+// The function must make its requests with the given context, the transport
+// does not retry those so that a request is not retried by both
+func (self *Drive) retry(fn func(ctx context.Context) error) error {
+	ctx := withCallerRetry(self.ctx)
+
+	for try := 0; ; try++ {
+		err := fn(ctx)
+		if err == nil || try >= self.retries.maxRetries || !isRetryableError(err) || self.interrupted() {
+			return err
+		}
+
+		select {
+		case <-time.After(retryDelay(err, try)):
+		case <-self.ctx.Done():
+			return err
+		}
+	}
+}
+
+// Error returned when a transfer is interrupted after it was started
+type interruptedError struct {
+	err error
+}
+
+func (self *interruptedError) Error() string {
+	return fmt.Sprintf("Transfer was interrupted: %s", self.err)
+}
+
+func isRetryableError(err error) bool {
+	if _, ok := err.(*interruptedError); ok {
+		return true
+	}
+	return isBackendOrRateLimitError(err)
+}
+
+// Returns how long to wait before the next attempt. The Retry-After header
+// is used when provided, otherwise an exponential backoff with jitter
+func retryDelay(err error, try int) time.Duration {
+	if ae, ok := err.(*googleapi.Error); ok && ae.Header != nil {
+		if delay, ok := parseRetryAfter(ae.Header.Get("Retry-After")); ok {
+			return delay
+		}
+	}
+
+	backoff := MaxRetryDelay
+	if try < 6 {
+		backoff = time.Duration(pow(2, try)) * time.Second
+	}
+
+	// Wait between half and the full backoff to spread out concurrent retries
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// Parses the Retry-After header, which is either seconds or a http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		delay := t.Sub(time.Now())
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
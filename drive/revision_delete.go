@@ -11,7 +11,21 @@ type DeleteRevisionArgs struct {
 	RevisionId string
 }
 
+func (args *DeleteRevisionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
 func (self *Drive) DeleteRevision(args DeleteRevisionArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
 	rev, err := self.service.Revisions.Get(args.FileId, args.RevisionId).Fields("originalFilename").Do()
 	if err != nil {
 		return fmt.Errorf("Failed to get revision: %s", err)
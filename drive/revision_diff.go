@@ -0,0 +1,291 @@
+package drive
+
+import (
+	"bytes"
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Max size of a revision that can be diffed
+const MaxDiffSize = 10 * 1024 * 1024
+
+// Max number of cells in the table used to find the longest common
+// subsequence of the changed lines, limits the memory used by a diff
+const maxDiffCells = 32 * 1024 * 1024
+
+type DiffRevisionsArgs struct {
+	Out             io.Writer
+	FileId          string
+	RevisionId      string
+	OtherRevisionId string
+	Context         int64
+}
+
+func (args *DiffRevisionsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+// Prints a unified diff between two revisions of a text file
+func (self *Drive) DiffRevisions(args DiffRevisionsArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
+	oldRev, oldText, err := self.readRevisionText(args.FileId, args.RevisionId)
+	if err != nil {
+		return err
+	}
+
+	newRev, newText, err := self.readRevisionText(args.FileId, args.OtherRevisionId)
+	if err != nil {
+		return err
+	}
+
+	ops, err := diffLines(splitLines(oldText), splitLines(newText))
+	if err != nil {
+		return err
+	}
+
+	hunks := diffHunks(ops, int(args.Context))
+	if len(hunks) == 0 {
+		fmt.Fprintln(args.Out, "Revisions are identical")
+		return nil
+	}
+
+	fmt.Fprintf(args.Out, "--- %s\t(revision %s, %s)\n", oldRev.OriginalFilename, oldRev.Id, formatDatetime(oldRev.ModifiedTime))
+	fmt.Fprintf(args.Out, "+++ %s\t(revision %s, %s)\n", newRev.OriginalFilename, newRev.Id, formatDatetime(newRev.ModifiedTime))
+
+	for _, h := range hunks {
+		h.print(args.Out)
+	}
+
+	return nil
+}
+
+func (self *Drive) readRevisionText(fileId, revId string) (*drive.Revision, string, error) {
+	getRev := self.service.Revisions.Get(fileId, revId)
+
+	rev, err := getRev.Fields("id", "mimeType", "modifiedTime", "originalFilename", "size").Do()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to get revision: %s", err)
+	}
+
+	if rev.OriginalFilename == "" {
+		return nil, "", fmt.Errorf("Diff is not supported for this file type")
+	}
+
+	if rev.Size > MaxDiffSize {
+		return nil, "", fmt.Errorf("Revision '%s' is too large to diff (%s)", rev.Id, formatSize(rev.Size, false))
+	}
+
+	res, err := getRev.Context(self.ctx).Download()
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to download file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	content, err := ioutil.ReadAll(io.LimitReader(res.Body, MaxDiffSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to download file: %s", err)
+	}
+
+	if len(content) > MaxDiffSize {
+		return nil, "", fmt.Errorf("Revision '%s' is too large to diff", rev.Id)
+	}
+
+	if !isTextContent(rev.MimeType, content) {
+		return nil, "", fmt.Errorf("Revision '%s' is not a text file (%s)", rev.Id, rev.MimeType)
+	}
+
+	return rev, string(content), nil
+}
+
+func isTextContent(mimeType string, content []byte) bool {
+	if strings.HasPrefix(mimeType, "text/") {
+		return true
+	}
+
+	// Look for null bytes in the first block, like most diff tools do
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) == -1
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+type diffOp struct {
+	kind byte // One of ' ', '-' or '+'
+	line string
+}
+
+// Returns the operations that turns a into b
+func diffLines(a, b []string) ([]diffOp, error) {
+	// Strip common prefix and suffix, they are kept as is
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	changed, err := diffChanged(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, changed...)
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	return ops, nil
+}
+
+// Diffs the lines using the longest common subsequence
+func diffChanged(a, b []string) ([]diffOp, error) {
+	n, m := len(a), len(b)
+	if (n+1)*(m+1) > maxDiffCells {
+		return nil, fmt.Errorf("Revisions differ too much to be diffed")
+	}
+
+	// lcs[i*(m+1)+j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([]int32, (n+1)*(m+1))
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+			} else if lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1] {
+				lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j]
+			} else {
+				lcs[i*(m+1)+j] = lcs[i*(m+1)+j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		if a[i] == b[j] {
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		} else if lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1] {
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		} else {
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops, nil
+}
+
+type diffHunk struct {
+	oldStart int
+	oldLines int
+	newStart int
+	newLines int
+	ops      []diffOp
+}
+
+func (self diffHunk) print(out io.Writer) {
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", self.oldStart, self.oldLines, self.newStart, self.newLines)
+	for _, op := range self.ops {
+		fmt.Fprintf(out, "%c%s\n", op.kind, op.line)
+	}
+}
+
+// Groups the changes into hunks with the given number of context lines
+func diffHunks(ops []diffOp, context int) []diffHunk {
+	if context < 0 {
+		context = 0
+	}
+
+	// Line numbers of both files before each operation
+	oldPos := make([]int, len(ops)+1)
+	newPos := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if op.kind != '+' {
+			oldPos[i+1]++
+		}
+		if op.kind != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var hunks []diffHunk
+	for k := 0; k < len(changes); {
+		first := changes[k]
+		last := first
+		for k++; k < len(changes) && changes[k]-last <= 2*context+1; k++ {
+			last = changes[k]
+		}
+
+		start := first - context
+		if start < 0 {
+			start = 0
+		}
+		end := last + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		h := diffHunk{
+			oldStart: oldPos[start] + 1,
+			oldLines: oldPos[end] - oldPos[start],
+			newStart: newPos[start] + 1,
+			newLines: newPos[end] - newPos[start],
+			ops:      ops[start:end],
+		}
+
+		// Unified diffs use the line before the hunk when it is empty
+		if h.oldLines == 0 {
+			h.oldStart--
+		}
+		if h.newLines == 0 {
+			h.newStart--
+		}
+
+		hunks = append(hunks, h)
+	}
+
+	return hunks
+}
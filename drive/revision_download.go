@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"path/filepath"
 	"time"
+
+	"google.golang.org/api/googleapi"
 )
 
 type DownloadRevisionArgs struct {
@@ -19,7 +22,21 @@ type DownloadRevisionArgs struct {
 	Timeout    time.Duration
 }
 
+func (args *DownloadRevisionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
 func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
 	getRev := self.service.Revisions.Get(args.FileId, args.RevisionId)
 
 	rev, err := getRev.Fields("originalFilename").Do()
@@ -32,11 +49,11 @@ func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
 	}
 
 	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(args.Timeout)
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, args.Timeout)
 
 	res, err := getRev.Context(ctx).Download()
 	if err != nil {
-		if isTimeoutError(err) {
+		if self.isTimeoutError(err) {
 			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
 		}
 		return fmt.Errorf("Failed to download file: %s", err)
@@ -73,3 +90,54 @@ func (self *Drive) DownloadRevision(args DownloadRevisionArgs) (err error) {
 	fmt.Fprintf(out, "Download complete, rate: %s/s, total size: %s\n", formatSize(rate, false), formatSize(bytes, false))
 	return nil
 }
+
+// Downloads the given revision to args.fpath
+func (self *Drive) saveRevision(fileId, revId string, timeout time.Duration, args saveFileArgs) (int64, int64, error) {
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, timeout)
+
+	res, err := self.service.Revisions.Get(fileId, revId).Context(ctx).Download()
+	if err != nil {
+		if self.isTimeoutError(err) {
+			return 0, 0, fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", timeout)
+		}
+		return 0, 0, fmt.Errorf("Failed to download file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	args.body = timeoutReaderWrapper(res.Body)
+	args.contentLength = res.ContentLength
+	return self.saveFile(args)
+}
+
+// Downloads the export of a google document revision from one of its export links
+func (self *Drive) saveRevisionExport(link string, timeout time.Duration, args saveFileArgs) (int64, int64, error) {
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(self.ctx, timeout)
+
+	req, err := http.NewRequest("GET", link, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	res, err := self.client.Do(req.WithContext(ctx))
+	if err != nil {
+		if self.isTimeoutError(err) {
+			return 0, 0, fmt.Errorf("Failed to export file: timeout, no data was transferred for %v", timeout)
+		}
+		return 0, 0, fmt.Errorf("Failed to export file: %s", err)
+	}
+
+	// Close body on function exit
+	defer res.Body.Close()
+
+	if err := googleapi.CheckResponse(res); err != nil {
+		return 0, 0, fmt.Errorf("Failed to export file: %s", err)
+	}
+
+	args.body = timeoutReaderWrapper(res.Body)
+	args.contentLength = res.ContentLength
+	return self.saveFile(args)
+}
@@ -0,0 +1,48 @@
+package drive
+
+import (
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"io"
+)
+
+type KeepRevisionArgs struct {
+	Out        io.Writer
+	FileId     string
+	RevisionId string
+	Keep       bool
+}
+
+func (args *KeepRevisionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+func (self *Drive) KeepRevision(args KeepRevisionArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
+	rev := &drive.Revision{
+		KeepForever: args.Keep,
+		// KeepForever is omitted from the request when false
+		ForceSendFields: []string{"KeepForever"},
+	}
+
+	rev, err = self.service.Revisions.Update(args.FileId, args.RevisionId, rev).Fields("id", "keepForever").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to update revision: %s", err)
+	}
+
+	if rev.KeepForever {
+		fmt.Fprintf(args.Out, "Revision '%s' will be kept forever\n", rev.Id)
+	} else {
+		fmt.Fprintf(args.Out, "Revision '%s' is no longer kept forever\n", rev.Id)
+	}
+	return
+}
@@ -15,7 +15,21 @@ type ListRevisionsArgs struct {
 	SizeInBytes bool
 }
 
+func (args *ListRevisionsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
 func (self *Drive) ListRevisions(args ListRevisionsArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
 	revList, err := self.service.Revisions.List(args.Id).Fields("revisions(id,keepForever,size,modifiedTime,originalFilename)").Do()
 	if err != nil {
 		return fmt.Errorf("Failed listing revisions: %s", err)
@@ -0,0 +1,193 @@
+package drive
+
+import (
+	"fmt"
+	"google.golang.org/api/drive/v3"
+	"io"
+	"net/url"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+type PruneRevisionsArgs struct {
+	Out         io.Writer
+	FileId      string
+	KeepLast    int64
+	OlderThan   time.Duration
+	DryRun      bool
+	SizeInBytes bool
+}
+
+func (args *PruneRevisionsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+// Deletes old revisions of a file. The newest KeepLast revisions and
+// revisions marked as keep forever are never deleted, when OlderThan is
+// given only revisions modified before that are deleted
+func (self *Drive) PruneRevisions(args PruneRevisionsArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
+	if args.KeepLast <= 0 && args.OlderThan <= 0 {
+		return fmt.Errorf("--keep-last or --older-than is required")
+	}
+
+	all, err := self.listAllRevisions(args.FileId, "id,keepForever,size,modifiedTime,originalFilename")
+	if err != nil {
+		return err
+	}
+
+	revisions := pruneCandidates(all, args.KeepLast, args.OlderThan, time.Now())
+	if len(revisions) == 0 {
+		fmt.Fprintln(args.Out, "No revisions to prune")
+		return nil
+	}
+
+	var size int64
+	for _, rev := range revisions {
+		size += rev.Size
+	}
+
+	printPrunedRevisions(args.Out, revisions, args.SizeInBytes)
+
+	if args.DryRun {
+		fmt.Fprintf(args.Out, "\nWould delete %d revisions, %s\n", len(revisions), formatSize(size, args.SizeInBytes))
+		return nil
+	}
+
+	var requests []*batchRequest
+	for _, rev := range revisions {
+		requests = append(requests, &batchRequest{
+			method: "DELETE",
+			path:   fmt.Sprintf("files/%s/revisions/%s", url.PathEscape(args.FileId), url.PathEscape(rev.Id)),
+		})
+	}
+
+	var failed int
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(args.Out, "Failed to delete revision '%s': %s\n", revisions[i].Id, result.err)
+			size -= revisions[i].Size
+			failed++
+		}
+	}
+
+	fmt.Fprintf(args.Out, "\nDeleted %d revisions, %s\n", len(revisions)-failed, formatSize(size, args.SizeInBytes))
+	return batchError("delete revisions of", failed, len(revisions))
+}
+
+// Returns all revisions of the file with the given revision fields.
+// The vendored client can not page through revisions, so the
+// pages are requested directly
+func (self *Drive) listAllRevisions(fileId, fields string) ([]*drive.Revision, error) {
+	var revisions []*drive.Revision
+	pageToken := ""
+
+	for {
+		query := url.Values{
+			"fields":   {fmt.Sprintf("nextPageToken,revisions(%s)", fields)},
+			"pageSize": {"1000"},
+		}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		result := self.executeRequest(&batchRequest{
+			method: "GET",
+			path:   "files/" + url.PathEscape(fileId) + "/revisions",
+			query:  query,
+		})
+
+		var list struct {
+			NextPageToken string            `json:"nextPageToken"`
+			Revisions     []*drive.Revision `json:"revisions"`
+		}
+		if err := result.decode(&list); err != nil {
+			return nil, fmt.Errorf("Failed listing revisions: %s", err)
+		}
+
+		revisions = append(revisions, list.Revisions...)
+		if list.NextPageToken == "" {
+			return revisions, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+// Returns the revisions that should be deleted, oldest first.
+// The head revision is always kept and revisions of google
+// documents are skipped as they can not be deleted
+func pruneCandidates(revisions []*drive.Revision, keepLast int64, olderThan time.Duration, now time.Time) []*drive.Revision {
+	sorted := make([]*drive.Revision, len(revisions))
+	copy(sorted, revisions)
+	sort.Stable(byRevisionModifiedTime(sorted))
+
+	keep := int(keepLast)
+	if keep < 1 {
+		keep = 1
+	}
+	if keep >= len(sorted) {
+		return nil
+	}
+
+	cutoff := now.Add(-olderThan)
+
+	var candidates []*drive.Revision
+	for _, rev := range sorted[:len(sorted)-keep] {
+		if rev.KeepForever || rev.OriginalFilename == "" {
+			continue
+		}
+
+		if olderThan > 0 {
+			modified, err := time.Parse(time.RFC3339, rev.ModifiedTime)
+			if err != nil || !modified.Before(cutoff) {
+				continue
+			}
+		}
+
+		candidates = append(candidates, rev)
+	}
+
+	return candidates
+}
+
+func printPrunedRevisions(out io.Writer, revisions []*drive.Revision, sizeInBytes bool) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Id\tName\tSize\tModified")
+
+	for _, rev := range revisions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			rev.Id,
+			rev.OriginalFilename,
+			formatSize(rev.Size, sizeInBytes),
+			formatDatetime(rev.ModifiedTime),
+		)
+	}
+
+	w.Flush()
+}
+
+type byRevisionModifiedTime []*drive.Revision
+
+func (self byRevisionModifiedTime) Len() int {
+	return len(self)
+}
+
+func (self byRevisionModifiedTime) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byRevisionModifiedTime) Less(i, j int) bool {
+	return self[i].ModifiedTime < self[j].ModifiedTime
+}
@@ -0,0 +1,106 @@
+package drive
+
+import (
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/api/drive/v3"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+type RestoreRevisionArgs struct {
+	Out        io.Writer
+	Progress   io.Writer
+	FileId     string
+	RevisionId string
+	ChunkSize  int64
+	Timeout    time.Duration
+}
+
+func (args *RestoreRevisionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+// Makes the given revision the head revision by uploading
+// its content as a new revision of the file
+func (self *Drive) RestoreRevision(args RestoreRevisionArgs) (err error) {
+	if err = args.normalize(self); err != nil {
+		return err
+	}
+
+	f, err := self.service.Files.Get(args.FileId).Fields("id", "name").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get file: %s", err)
+	}
+
+	getRev := self.service.Revisions.Get(args.FileId, args.RevisionId)
+
+	rev, err := getRev.Fields("id", "mimeType", "originalFilename").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to get revision: %s", err)
+	}
+
+	if rev.OriginalFilename == "" {
+		return fmt.Errorf("Restoring revisions for this file type is not supported")
+	}
+
+	// Keep the revision content in a temporary file so the upload can be retried
+	tmpFile, err := ioutil.TempFile("", "gdrive-revision-")
+	if err != nil {
+		return fmt.Errorf("Failed to create temporary file: %s", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	fmt.Fprintf(args.Out, "Downloading revision '%s'\n", rev.Id)
+
+	_, _, err = self.saveRevision(args.FileId, rev.Id, args.Timeout, saveFileArgs{
+		out:      ioutil.Discard,
+		fpath:    tmpFile.Name(),
+		force:    true,
+		progress: args.Progress,
+	})
+	if err != nil {
+		return err
+	}
+
+	return self.uploadRevision(f, rev, tmpFile.Name(), args)
+}
+
+// Uploads the content of a downloaded revision as the new head revision of the file
+func (self *Drive) uploadRevision(f *drive.File, rev *drive.Revision, path string, args RestoreRevisionArgs) error {
+	fmt.Fprintf(args.Out, "Uploading revision '%s' as the head revision of %s\n", rev.Id, f.Name)
+
+	updateArgs := UpdateArgs{
+		Out:       ioutil.Discard,
+		Progress:  args.Progress,
+		Id:        f.Id,
+		Path:      path,
+		Name:      f.Name,
+		Mime:      rev.MimeType,
+		ChunkSize: args.ChunkSize,
+		Timeout:   args.Timeout,
+	}
+
+	err := self.retry(func(ctx context.Context) error {
+		_, _, err := self.updateFile(ctx, updateArgs)
+		return err
+	})
+	if err != nil {
+		if isRetryableError(err) {
+			return fmt.Errorf("Failed to upload file: %s", err)
+		}
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Restored revision '%s' of %s\n", rev.Id, f.Name)
+	return nil
+}
@@ -0,0 +1,156 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+type SearchArgs struct {
+	Out io.Writer
+	// Download messages are written here with json output,
+	// to keep the output valid json
+	Status   io.Writer
+	Progress io.Writer
+	Terms    string
+	// Only In and MimeType are commonly used, but all filters apply
+	Filter       QueryFilter
+	MaxFiles     int64
+	Format       string
+	SnippetWidth int64
+	SkipHeader   bool
+
+	// Download the results to Path
+	Download  bool
+	Recursive bool
+	Path      string
+	Force     bool
+	Skip      bool
+	Timeout   time.Duration
+}
+
+// A search result in the json output
+type searchResult struct {
+	Rank     int    `json:"rank"`
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	MimeType string `json:"mimeType"`
+	Modified string `json:"modifiedTime"`
+	Snippet  string `json:"snippet,omitempty"`
+}
+
+// Searches the content and metadata of files. Results are ranked by
+// relevance by drive. The api does not return matching text, the
+// description of the file is used as snippet when it has one
+func (self *Drive) Search(args SearchArgs) error {
+	format := strings.ToLower(args.Format)
+	if format != "table" && format != "json" {
+		return fmt.Errorf("Unknown output format '%s', valid formats are: table, json", args.Format)
+	}
+
+	filter := args.Filter
+	filter.FullText = args.Terms
+
+	query, err := self.buildQuery("", filter)
+	if err != nil {
+		return err
+	}
+
+	// Results of full text queries can not be sorted, they are
+	// returned in order of relevance
+	files, err := self.listAllFiles(listAllFilesArgs{
+		query:    query,
+		fields:   []googleapi.Field{"nextPageToken", "files(id,name,md5Checksum,mimeType,size,modifiedTime,parents,description)"},
+		maxFiles: args.MaxFiles,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to search files: %s", err)
+	}
+
+	finder := self.newPathFinder()
+	if err := finder.PrefetchParents(files); err != nil {
+		return err
+	}
+
+	var results []*searchResult
+	for i, f := range files {
+		absPath, err := finder.GetAbsPath(f)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, &searchResult{
+			Rank:     i + 1,
+			Id:       f.Id,
+			Name:     f.Name,
+			Path:     absPath,
+			MimeType: f.MimeType,
+			Modified: f.ModifiedTime,
+			Snippet:  strings.Join(strings.Fields(f.Description), " "),
+		})
+	}
+
+	if format == "json" {
+		err = writeSearchJson(args.Out, results)
+	} else {
+		printSearchResults(args, results)
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write results: %s", err)
+	}
+
+	if !args.Download {
+		return nil
+	}
+
+	out := args.Out
+	if format == "json" {
+		out = args.Status
+	}
+
+	return self.downloadFiles(files, args.Recursive, DownloadArgs{
+		Out:      out,
+		Progress: args.Progress,
+		Path:     args.Path,
+		Force:    args.Force,
+		Skip:     args.Skip,
+		Timeout:  args.Timeout,
+	})
+}
+
+func printSearchResults(args SearchArgs, results []*searchResult) {
+	w := new(tabwriter.Writer)
+	w.Init(args.Out, 0, 0, 3, ' ', 0)
+
+	if !args.SkipHeader {
+		fmt.Fprintln(w, "Rank\tId\tPath\tModified\tSnippet")
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+			r.Rank,
+			r.Id,
+			r.Path,
+			formatDatetime(r.Modified),
+			truncateString(r.Snippet, int(args.SnippetWidth)),
+		)
+	}
+
+	w.Flush()
+}
+
+func writeSearchJson(out io.Writer, results []*searchResult) error {
+	if results == nil {
+		results = []*searchResult{}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
@@ -3,36 +3,427 @@ package drive
 import (
 	"fmt"
 	"io"
+	"net/url"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"google.golang.org/api/drive/v3"
 )
 
+// A permission as sent to and returned by the api. The vendored
+// client does not support expiration times, so permissions are
+// managed with plain requests, see executeRequest
+type permission struct {
+	Id                 string `json:"id,omitempty"`
+	Role               string `json:"role,omitempty"`
+	Type               string `json:"type,omitempty"`
+	EmailAddress       string `json:"emailAddress,omitempty"`
+	Domain             string `json:"domain,omitempty"`
+	AllowFileDiscovery bool   `json:"allowFileDiscovery,omitempty"`
+	ExpirationTime     string `json:"expirationTime,omitempty"`
+	// Only set for files in shared drives
+	PermissionDetails []*permissionDetail `json:"permissionDetails,omitempty"`
+}
+
+type permissionDetail struct {
+	PermissionType string `json:"permissionType"`
+	Role           string `json:"role"`
+	Inherited      bool   `json:"inherited"`
+	InheritedFrom  string `json:"inheritedFrom,omitempty"`
+}
+
+// Returns true if the permission only exists because it is inherited from a parent
+func (self *permission) inherited() bool {
+	if len(self.PermissionDetails) == 0 {
+		return false
+	}
+
+	for _, d := range self.PermissionDetails {
+		if !d.Inherited {
+			return false
+		}
+	}
+	return true
+}
+
+type permissionList struct {
+	NextPageToken string        `json:"nextPageToken"`
+	Permissions   []*permission `json:"permissions"`
+}
+
+const permissionFields = "nextPageToken,permissions(id,role,type,domain,emailAddress,allowFileDiscovery,expirationTime,permissionDetails)"
+
+// Max permissions per page, shared drives return at most 100
+const permissionPageSize = "100"
+
 type ShareArgs struct {
-	Out          io.Writer
-	FileId       string
-	Role         string
-	Type         string
-	Email        string
-	Domain       string
-	Discoverable bool
+	Out               io.Writer
+	FileId            string
+	Role              string
+	Type              string
+	Email             string
+	Domain            string
+	Discoverable      bool
+	Expires           time.Time
+	Notify            bool
+	Message           string
+	TransferOwnership bool
+	Recursive         bool
+	Revoke            bool
+	DryRun            bool
 }
 
 func (self *Drive) Share(args ShareArgs) error {
-	permission := &drive.Permission{
+	ids, err := self.resolveFileIds(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	if args.TransferOwnership && (args.Type != "user" || args.Email == "") {
+		return fmt.Errorf("--transfer-ownership requires 'user' as type and an email")
+	}
+
+	// Map of file id -> parent id within the tree
+	parents := map[string]string{}
+
+	if args.Recursive {
+		tree, err := self.fileTree(ids, "id,name,mimeType")
+		if err != nil {
+			return err
+		}
+
+		var files []*drive.File
+		for _, tf := range tree {
+			files = append(files, tf.file)
+			if tf.parent != nil {
+				parents[tf.file.Id] = tf.parent.file.Id
+			}
+		}
+
+		if args.DryRun {
+			printShareSummary(args, files)
+			return nil
+		}
+
+		ids = nil
+		for _, f := range files {
+			ids = append(ids, f.Id)
+		}
+	} else if args.DryRun {
+		files, _ := self.batchGetFiles(ids, "id", "name", "mimeType")
+		printShareSummary(args, files)
+		return nil
+	}
+
+	if args.Revoke {
+		return self.revokeMatching(ids, parents, args)
+	}
+
+	if len(ids) > 1 {
+		return self.shareBatch(ids, args)
+	}
+
+	for _, id := range ids {
+		fileArgs := args
+		fileArgs.FileId = id
+
+		err = self.shareOne(fileArgs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (self *Drive) shareOne(args ShareArgs) error {
+	result := self.executeRequest(args.request(args.FileId))
+	if result.err != nil {
+		return fmt.Errorf("Failed to share file: %s", result.err)
+	}
+
+	fmt.Fprintf(args.Out, "Granted %s permission to %s\n", args.role(), args.Type)
+	return nil
+}
+
+func (args ShareArgs) role() string {
+	if args.TransferOwnership {
+		return "owner"
+	}
+	return args.Role
+}
+
+func (args ShareArgs) permission() *permission {
+	p := &permission{
 		AllowFileDiscovery: args.Discoverable,
-		Role:               args.Role,
+		Role:               args.role(),
 		Type:               args.Type,
 		EmailAddress:       args.Email,
 		Domain:             args.Domain,
 	}
 
-	_, err := self.service.Permissions.Create(args.FileId, permission).Do()
+	if !args.Expires.IsZero() {
+		p.ExpirationTime = args.Expires.UTC().Format(time.RFC3339)
+	}
+
+	return p
+}
+
+func (args ShareArgs) request(fileId string) *batchRequest {
+	query := fieldsQuery("id")
+
+	// Notification emails can only be sent to users and groups,
+	// they are required when transferring ownership
+	if args.Type == "user" || args.Type == "group" {
+		notify := args.Notify || args.Message != "" || args.TransferOwnership
+		query.Set("sendNotificationEmail", strconv.FormatBool(notify))
+		if args.Message != "" {
+			query.Set("emailMessage", args.Message)
+		}
+	}
+
+	if args.TransferOwnership {
+		query.Set("transferOwnership", "true")
+	}
+
+	return &batchRequest{
+		method: "POST",
+		path:   fmt.Sprintf("files/%s/permissions", url.PathEscape(fileId)),
+		query:  query,
+		body:   args.permission(),
+	}
+}
+
+// Returns true if the permission is the one described by the args, the role is ignored
+func (args ShareArgs) matches(p *permission) bool {
+	if p.Role == "owner" || p.Type != args.Type {
+		return false
+	}
+
+	if args.Email != "" && !strings.EqualFold(p.EmailAddress, args.Email) {
+		return false
+	}
+
+	return args.Domain == "" || strings.EqualFold(p.Domain, args.Domain)
+}
+
+// Shares multiple files using batch requests
+func (self *Drive) shareBatch(ids []string, args ShareArgs) error {
+	var requests []*batchRequest
+	for _, id := range ids {
+		requests = append(requests, args.request(id))
+	}
+
+	var failed int
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(args.Out, "Failed to share '%s': %s\n", ids[i], result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(args.Out, "Granted %s permission to %s on '%s'\n", args.role(), args.Type, ids[i])
+	}
+
+	return batchError("share", failed, len(ids))
+}
+
+// Revokes the permissions matching the args from the given files. Inherited
+// permissions are only revoked on the highest ancestor that grants them, given
+// the map of file id -> parent id. Revoking them on the children fails, as
+// shared drives reject it and in my drive they are gone with the parent grant
+func (self *Drive) revokeMatching(ids []string, parents map[string]string, args ShareArgs) error {
+	permissions, errors := self.batchListPermissions(ids)
+
+	// Map of file id -> ids of the matching permissions
+	matching := map[string]map[string]bool{}
+	for i, id := range ids {
+		matching[id] = map[string]bool{}
+		for _, p := range permissions[i] {
+			if args.matches(p) {
+				matching[id][p.Id] = true
+			}
+		}
+	}
+
+	var requests []*batchRequest
+	var revoked []string
+	var failed, listFailed int
+
+	for i, id := range ids {
+		if errors[i] != nil {
+			fmt.Fprintf(args.Out, "Failed to list permissions of '%s': %s\n", id, errors[i])
+			listFailed++
+			continue
+		}
+
+		for _, p := range permissions[i] {
+			if !args.matches(p) || p.inherited() || grantedByAncestor(id, p.Id, parents, matching) {
+				continue
+			}
+
+			requests = append(requests, &batchRequest{
+				method: "DELETE",
+				path:   fmt.Sprintf("files/%s/permissions/%s", url.PathEscape(id), url.PathEscape(p.Id)),
+			})
+			revoked = append(revoked, id)
+		}
+	}
+
+	for i, result := range self.executeBatch(requests) {
+		if result.err != nil {
+			fmt.Fprintf(args.Out, "Failed to revoke permission on '%s': %s\n", revoked[i], result.err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(args.Out, "Revoked %s permission on '%s'\n", args.Type, revoked[i])
+	}
+
+	if len(requests) == 0 && listFailed == 0 {
+		fmt.Fprintln(args.Out, "No matching permissions found")
+	}
+
+	return batchError("revoke permissions of", failed+listFailed, len(requests)+listFailed)
+}
+
+// Returns true if an ancestor of the file has the permission, given the map of
+// file id -> parent id and the map of file id -> permission ids. Outside of
+// shared drives the api does not tell whether a permission is inherited,
+// but it has the same id as the permission of the parent
+func grantedByAncestor(id, permissionId string, parents map[string]string, permissionIds map[string]map[string]bool) bool {
+	for parent, ok := parents[id]; ok; parent, ok = parents[parent] {
+		if permissionIds[parent][permissionId] {
+			return true
+		}
+	}
+	return false
+}
+
+// Lists the permissions of the given files using batch requests. Files
+// with more permissions than fit on a page are listed again with the page
+// token until all pages are read
+func (self *Drive) batchListPermissions(ids []string) ([][]*permission, []error) {
+	permissions := make([][]*permission, len(ids))
+	errors := make([]error, len(ids))
+	pageTokens := make([]string, len(ids))
+
+	// Indexes of the files with pages left to list
+	pending := make([]int, len(ids))
+	for i := range ids {
+		pending[i] = i
+	}
+
+	for len(pending) > 0 {
+		var requests []*batchRequest
+		for _, i := range pending {
+			query := url.Values{"fields": {permissionFields}, "pageSize": {permissionPageSize}}
+			if pageTokens[i] != "" {
+				query.Set("pageToken", pageTokens[i])
+			}
+
+			requests = append(requests, &batchRequest{
+				method: "GET",
+				path:   fmt.Sprintf("files/%s/permissions", url.PathEscape(ids[i])),
+				query:  query,
+			})
+		}
+
+		var next []int
+		for j, result := range self.executeBatch(requests) {
+			i := pending[j]
+
+			list := &permissionList{}
+			if err := result.decode(list); err != nil {
+				errors[i] = err
+				continue
+			}
+
+			permissions[i] = append(permissions[i], list.Permissions...)
+			if list.NextPageToken != "" {
+				pageTokens[i] = list.NextPageToken
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+
+	return permissions, errors
+}
+
+func printShareSummary(args ShareArgs, files []*drive.File) {
+	var dirs int
+	for _, f := range files {
+		if f != nil && isDir(f) {
+			dirs++
+		}
+	}
+
+	if args.Revoke {
+		fmt.Fprintf(args.Out, "Would revoke %s permissions on %d files and %d directories\n", args.Type, len(files)-dirs, dirs)
+	} else {
+		fmt.Fprintf(args.Out, "Would grant %s permission to %s on %d files and %d directories\n", args.role(), args.Type, len(files)-dirs, dirs)
+	}
+}
+
+type UpdatePermissionArgs struct {
+	Out               io.Writer
+	FileId            string
+	PermissionId      string
+	Role              string
+	Expires           time.Time
+	TransferOwnership bool
+}
+
+func (args *UpdatePermissionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
 	if err != nil {
-		return fmt.Errorf("Failed to share file: %s", err)
+		return err
 	}
 
-	fmt.Fprintf(args.Out, "Granted %s permission to %s\n", args.Role, args.Type)
+	args.FileId = id
+	return nil
+}
+
+func (self *Drive) UpdatePermission(args UpdatePermissionArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	p := &permission{Role: args.Role}
+	if args.TransferOwnership {
+		p.Role = "owner"
+	}
+	if !args.Expires.IsZero() {
+		p.ExpirationTime = args.Expires.UTC().Format(time.RFC3339)
+	}
+
+	if p.Role == "" && p.ExpirationTime == "" {
+		return fmt.Errorf("Nothing to update, use --role, --expires or --transfer-ownership")
+	}
+
+	query := fieldsQuery("id", "role", "expirationTime")
+	if args.TransferOwnership {
+		query.Set("transferOwnership", "true")
+	}
+
+	result := self.executeRequest(&batchRequest{
+		method: "PATCH",
+		path:   fmt.Sprintf("files/%s/permissions/%s", url.PathEscape(args.FileId), url.PathEscape(args.PermissionId)),
+		query:  query,
+		body:   p,
+	})
+
+	updated := &permission{}
+	if err := result.decode(updated); err != nil {
+		return fmt.Errorf("Failed to update permission: %s", err)
+	}
+
+	if updated.ExpirationTime != "" {
+		fmt.Fprintf(args.Out, "Permission updated, role: %s, expires: %s\n", updated.Role, formatDatetime(updated.ExpirationTime))
+	} else {
+		fmt.Fprintf(args.Out, "Permission updated, role: %s\n", updated.Role)
+	}
 	return nil
 }
 
@@ -42,7 +433,21 @@ type RevokePermissionArgs struct {
 	PermissionId string
 }
 
+func (args *RevokePermissionArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
+	if err != nil {
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
 func (self *Drive) RevokePermission(args RevokePermissionArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
 	err := self.service.Permissions.Delete(args.FileId, args.PermissionId).Do()
 	if err != nil {
 		return fmt.Errorf("Failed to revoke permission: %s", err)
@@ -57,15 +462,29 @@ type ListPermissionsArgs struct {
 	FileId string
 }
 
-func (self *Drive) ListPermissions(args ListPermissionsArgs) error {
-	permList, err := self.service.Permissions.List(args.FileId).Fields("permissions(id,role,type,domain,emailAddress,allowFileDiscovery)").Do()
+func (args *ListPermissionsArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.FileId)
 	if err != nil {
-		return fmt.Errorf("Failed to list permissions: %s", err)
+		return err
+	}
+
+	args.FileId = id
+	return nil
+}
+
+func (self *Drive) ListPermissions(args ListPermissionsArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	permissions, errors := self.batchListPermissions([]string{args.FileId})
+	if errors[0] != nil {
+		return fmt.Errorf("Failed to list permissions: %s", errors[0])
 	}
 
 	printPermissions(printPermissionsArgs{
 		out:         args.Out,
-		permissions: permList.Permissions,
+		permissions: permissions[0],
 	})
 	return nil
 }
@@ -86,23 +505,24 @@ func (self *Drive) shareAnyoneReader(fileId string) error {
 
 type printPermissionsArgs struct {
 	out         io.Writer
-	permissions []*drive.Permission
+	permissions []*permission
 }
 
 func printPermissions(args printPermissionsArgs) {
 	w := new(tabwriter.Writer)
 	w.Init(args.out, 0, 0, 3, ' ', 0)
 
-	fmt.Fprintln(w, "Id\tType\tRole\tEmail\tDomain\tDiscoverable")
+	fmt.Fprintln(w, "Id\tType\tRole\tEmail\tDomain\tDiscoverable\tExpires")
 
 	for _, p := range args.permissions {
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			p.Id,
 			p.Type,
 			p.Role,
 			p.EmailAddress,
 			p.Domain,
 			formatBool(p.AllowFileDiscovery),
+			formatDatetime(p.ExpirationTime),
 		)
 	}
 
@@ -0,0 +1,344 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Desired permissions of a set of folders. Json is valid yaml,
+// so policies can be written in either format
+type SharePolicy struct {
+	Folders []*PolicyFolder `yaml:"folders"`
+}
+
+type PolicyFolder struct {
+	// Either the path or the id of the folder
+	Path        string              `yaml:"path,omitempty"`
+	Id          string              `yaml:"id,omitempty"`
+	Permissions []*PolicyPermission `yaml:"permissions"`
+}
+
+type PolicyPermission struct {
+	Type         string `yaml:"type"`
+	Role         string `yaml:"role"`
+	Email        string `yaml:"email,omitempty"`
+	Domain       string `yaml:"domain,omitempty"`
+	Discoverable bool   `yaml:"discoverable,omitempty"`
+}
+
+// Identifies the grantee of a permission, roles are not part of the key
+func (self *PolicyPermission) key() string {
+	return permissionKey(self.Type, self.Email, self.Domain)
+}
+
+func permissionKey(typ, email, domain string) string {
+	return strings.ToLower(fmt.Sprintf("%s:%s:%s", typ, email, domain))
+}
+
+func (self *PolicyPermission) String() string {
+	switch self.Type {
+	case "user", "group":
+		return fmt.Sprintf("%s %s", self.Type, self.Email)
+	case "domain":
+		return fmt.Sprintf("%s %s", self.Type, self.Domain)
+	}
+	return self.Type
+}
+
+var policyRoles = []string{"reader", "commenter", "writer", "fileOrganizer", "organizer"}
+
+func (self *PolicyPermission) validate() error {
+	switch self.Type {
+	case "user", "group":
+		if self.Email == "" {
+			return fmt.Errorf("email is required for type '%s'", self.Type)
+		}
+	case "domain":
+		if self.Domain == "" {
+			return fmt.Errorf("domain is required for type 'domain'")
+		}
+	case "anyone":
+	default:
+		return fmt.Errorf("unknown type '%s', valid types are: user, group, domain, anyone", self.Type)
+	}
+
+	if self.Discoverable && self.Type != "domain" && self.Type != "anyone" {
+		return fmt.Errorf("discoverable is only valid for the types domain and anyone, not for %s", self)
+	}
+
+	for _, role := range policyRoles {
+		if self.Role == role {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid role '%s' for %s, valid roles are: %s", self.Role, self, strings.Join(policyRoles, ", "))
+}
+
+func readSharePolicy(path string) (*SharePolicy, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read policy: %s", err)
+	}
+
+	policy := &SharePolicy{}
+	if err := yaml.UnmarshalStrict(content, policy); err != nil {
+		return nil, fmt.Errorf("Failed to parse policy: %s", err)
+	}
+
+	for i, folder := range policy.Folders {
+		if (folder.Path == "") == (folder.Id == "") {
+			return nil, fmt.Errorf("Invalid policy: folder %d must have either a path or an id", i+1)
+		}
+
+		seen := map[string]bool{}
+		for _, p := range folder.Permissions {
+			if err := p.validate(); err != nil {
+				return nil, fmt.Errorf("Invalid policy for %s: %s", folder.name(), err)
+			}
+			if seen[p.key()] {
+				return nil, fmt.Errorf("Invalid policy for %s: %s is listed more than once", folder.name(), p)
+			}
+			seen[p.key()] = true
+		}
+	}
+
+	return policy, nil
+}
+
+func (self *PolicyFolder) name() string {
+	if self.Path != "" {
+		return self.Path
+	}
+	return self.Id
+}
+
+// A change needed to reach the desired state. A change with both add and
+// remove replaces the permission, as discoverability can not be updated
+type policyChange struct {
+	folderId string
+	add      *PolicyPermission
+	update   *PolicyPermission
+	remove   *permission
+	current  *permission
+}
+
+func (self *policyChange) String() string {
+	switch {
+	case self.add != nil && self.remove != nil:
+		return fmt.Sprintf("~ %s -> %s %s, discoverable: %t -> %t", self.remove.Role, self.add.Role, self.add, self.remove.AllowFileDiscovery, self.add.Discoverable)
+	case self.add != nil:
+		return fmt.Sprintf("+ %s %s", self.add.Role, self.add)
+	case self.update != nil:
+		return fmt.Sprintf("~ %s -> %s %s", self.current.Role, self.update.Role, self.update)
+	}
+
+	p := &PolicyPermission{Type: self.remove.Type, Email: self.remove.EmailAddress, Domain: self.remove.Domain}
+	return fmt.Sprintf("- %s %s", self.remove.Role, p)
+}
+
+type ApplySharePolicyArgs struct {
+	Out        io.Writer
+	PolicyPath string
+	DryRun     bool
+}
+
+// Makes the permissions of the folders in the policy match the policy.
+// Permissions that are not in the policy are revoked, owners and
+// inherited permissions are left as is
+func (self *Drive) ApplySharePolicy(args ApplySharePolicyArgs) error {
+	policy, err := readSharePolicy(args.PolicyPath)
+	if err != nil {
+		return err
+	}
+
+	finder := self.newPathFinder()
+
+	var ids []string
+	for _, folder := range policy.Folders {
+		id := folder.Id
+		if id == "" {
+			id, err = finder.ResolveFileId(folder.Path)
+			if err != nil {
+				return err
+			}
+		}
+		ids = append(ids, id)
+	}
+
+	current, errors := self.batchListPermissions(ids)
+
+	inherited, err := self.inheritedPermissionIds(ids)
+	if err != nil {
+		return err
+	}
+
+	var changes []*policyChange
+	for i, folder := range policy.Folders {
+		if errors[i] != nil {
+			return fmt.Errorf("Failed to list permissions of %s: %s", folder.name(), errors[i])
+		}
+
+		folderChanges := diffPermissions(ids[i], folder.Permissions, current[i], inherited[i])
+		if len(folderChanges) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(args.Out, "%s (%s)\n", folder.name(), ids[i])
+		for _, c := range folderChanges {
+			fmt.Fprintf(args.Out, "  %s\n", c)
+		}
+		changes = append(changes, folderChanges...)
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintln(args.Out, "Permissions match the policy")
+		return nil
+	}
+
+	if args.DryRun {
+		return nil
+	}
+
+	var failed int
+	for _, c := range changes {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		if err := self.applyPolicyChange(c); err != nil {
+			fmt.Fprintf(args.Out, "%s\n", err)
+			failed++
+		}
+	}
+
+	fmt.Fprintf(args.Out, "Applied %d of %d changes\n", len(changes)-failed, len(changes))
+	return batchError("apply changes to", failed, len(changes))
+}
+
+// Returns the ids of the permissions each folder inherits from its parents.
+// Outside of shared drives the api does not tell whether a permission is
+// inherited, but it has the same id as the permission of the parent
+func (self *Drive) inheritedPermissionIds(ids []string) ([]map[string]bool, error) {
+	files, errors := self.batchGetFiles(ids, "id", "parents")
+
+	var parentIds []string
+	for i, f := range files {
+		if errors[i] != nil {
+			return nil, fmt.Errorf("Failed to get folder %s: %s", ids[i], errors[i])
+		}
+		parentIds = append(parentIds, f.Parents...)
+	}
+
+	permissions, errors := self.batchListPermissions(parentIds)
+
+	// Map of parent id -> permission ids
+	parentPermissions := map[string]map[string]bool{}
+	for i, id := range parentIds {
+		if errors[i] != nil {
+			return nil, fmt.Errorf("Failed to list permissions of parent %s: %s", id, errors[i])
+		}
+
+		parentPermissions[id] = map[string]bool{}
+		for _, p := range permissions[i] {
+			parentPermissions[id][p.Id] = true
+		}
+	}
+
+	inherited := make([]map[string]bool, len(ids))
+	for i, f := range files {
+		inherited[i] = map[string]bool{}
+		for _, parent := range f.Parents {
+			for id := range parentPermissions[parent] {
+				inherited[i][id] = true
+			}
+		}
+	}
+
+	return inherited, nil
+}
+
+// Returns the changes needed to turn the current permissions into the desired
+// ones, given the ids of the permissions inherited from the parents
+func diffPermissions(folderId string, desired []*PolicyPermission, current []*permission, inherited map[string]bool) []*policyChange {
+	existing := map[string]*permission{}
+	for _, p := range current {
+		existing[permissionKey(p.Type, p.EmailAddress, p.Domain)] = p
+	}
+
+	var changes []*policyChange
+	wanted := map[string]bool{}
+
+	for _, d := range desired {
+		wanted[d.key()] = true
+
+		p, found := existing[d.key()]
+		switch {
+		case !found:
+			changes = append(changes, &policyChange{folderId: folderId, add: d})
+		case p.Role == "owner":
+		case p.inherited() || inherited[p.Id]:
+			// Inherited permissions can not be changed on the folder,
+			// a direct permission is needed to grant a different role
+			if p.Role != d.Role {
+				changes = append(changes, &policyChange{folderId: folderId, add: d})
+			}
+		case p.AllowFileDiscovery != d.Discoverable:
+			changes = append(changes, &policyChange{folderId: folderId, add: d, remove: p})
+		case p.Role != d.Role:
+			changes = append(changes, &policyChange{folderId: folderId, update: d, current: p})
+		}
+	}
+
+	for _, p := range current {
+		if p.Role == "owner" || p.inherited() || inherited[p.Id] || wanted[permissionKey(p.Type, p.EmailAddress, p.Domain)] {
+			continue
+		}
+		changes = append(changes, &policyChange{folderId: folderId, remove: p})
+	}
+
+	return changes
+}
+
+func (self *Drive) applyPolicyChange(c *policyChange) error {
+	switch {
+	case c.add != nil && c.remove != nil:
+		err := self.RevokePermission(RevokePermissionArgs{
+			Out:          ioutil.Discard,
+			FileId:       c.folderId,
+			PermissionId: c.remove.Id,
+		})
+		if err != nil {
+			return err
+		}
+		return self.applyPolicyChange(&policyChange{folderId: c.folderId, add: c.add})
+
+	case c.add != nil:
+		return self.Share(ShareArgs{
+			Out:          ioutil.Discard,
+			FileId:       c.folderId,
+			Role:         c.add.Role,
+			Type:         c.add.Type,
+			Email:        c.add.Email,
+			Domain:       c.add.Domain,
+			Discoverable: c.add.Discoverable,
+		})
+
+	case c.update != nil:
+		return self.UpdatePermission(UpdatePermissionArgs{
+			Out:          ioutil.Discard,
+			FileId:       c.folderId,
+			PermissionId: c.current.Id,
+			Role:         c.update.Role,
+		})
+	}
+
+	return self.RevokePermission(RevokePermissionArgs{
+		Out:          ioutil.Discard,
+		FileId:       c.folderId,
+		PermissionId: c.remove.Id,
+	})
+}
@@ -0,0 +1,151 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const ShortcutMimeType = "application/vnd.google-apps.shortcut"
+
+// The vendored api client does not know about shortcuts,
+// they are created with plain requests, see executeRequest
+type shortcut struct {
+	Id              string           `json:"id,omitempty"`
+	Name            string           `json:"name,omitempty"`
+	MimeType        string           `json:"mimeType,omitempty"`
+	Parents         []string         `json:"parents,omitempty"`
+	ShortcutDetails *shortcutDetails `json:"shortcutDetails,omitempty"`
+}
+
+type shortcutDetails struct {
+	TargetId       string `json:"targetId,omitempty"`
+	TargetMimeType string `json:"targetMimeType,omitempty"`
+}
+
+type CreateShortcutArgs struct {
+	Out      io.Writer
+	TargetId string
+	ParentId string
+	Name     string
+}
+
+func (args *CreateShortcutArgs) normalize(drive *Drive) error {
+	ids, err := drive.resolveFileIdList([]string{args.TargetId, args.ParentId})
+	if err != nil {
+		return err
+	}
+
+	args.TargetId = ids[0]
+	args.ParentId = ids[1]
+	return nil
+}
+
+func (self *Drive) CreateShortcut(args CreateShortcutArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	// Use the name of the target by default
+	if args.Name == "" {
+		target, err := self.service.Files.Get(args.TargetId).Fields("name").Do()
+		if err != nil {
+			return fmt.Errorf("Failed to get file: %s", err)
+		}
+		args.Name = target.Name
+	}
+
+	created := &shortcut{}
+	result := self.executeRequest(shortcutRequest(args.Name, args.TargetId, args.ParentId))
+	if err := result.decode(created); err != nil {
+		return fmt.Errorf("Failed to create shortcut: %s", err)
+	}
+
+	fmt.Fprintf(args.Out, "Shortcut %s created\n", created.Id)
+	return nil
+}
+
+func isShortcut(f *drive.File) bool {
+	return f.MimeType == ShortcutMimeType
+}
+
+// Returns the target of the shortcut, see shortcutTargets
+func (self *Drive) shortcutTarget(f *drive.File, fields ...googleapi.Field) (*drive.File, error) {
+	targets, errors := self.shortcutTargets([]*drive.File{f}, fields...)
+	return targets[0], errors[0]
+}
+
+// Returns the targets of the shortcuts with the given fields. The
+// targets are returned in the same order as the shortcuts, each with
+// its own error
+func (self *Drive) shortcutTargets(shortcuts []*drive.File, fields ...googleapi.Field) ([]*drive.File, []error) {
+	var requests []*batchRequest
+	for _, f := range shortcuts {
+		requests = append(requests, &batchRequest{
+			method: "GET",
+			path:   "files/" + url.PathEscape(f.Id),
+			query:  url.Values{"fields": {"shortcutDetails(targetId)"}},
+		})
+	}
+
+	targets := make([]*drive.File, len(shortcuts))
+	errors := make([]error, len(shortcuts))
+
+	var ids []string
+	var indexes []int
+
+	for i, result := range self.executeBatch(requests) {
+		s := &shortcut{}
+		if err := result.decode(s); err != nil {
+			errors[i] = fmt.Errorf("Failed to get shortcut '%s': %s", shortcuts[i].Name, err)
+			continue
+		}
+
+		if s.ShortcutDetails == nil || s.ShortcutDetails.TargetId == "" {
+			errors[i] = fmt.Errorf("'%s' is not a shortcut", shortcuts[i].Name)
+			continue
+		}
+
+		ids = append(ids, s.ShortcutDetails.TargetId)
+		indexes = append(indexes, i)
+	}
+
+	files, fileErrors := self.batchGetFiles(ids, fields...)
+	for j, i := range indexes {
+		if fileErrors[j] != nil {
+			errors[i] = fmt.Errorf("Failed to get target of shortcut '%s': %s", shortcuts[i].Name, fileErrors[j])
+			continue
+		}
+		targets[i] = files[j]
+	}
+
+	return targets, errors
+}
+
+// Returns a request creating a shortcut to the target in the given parent
+func shortcutRequest(name, targetId, parentId string) *batchRequest {
+	return &batchRequest{
+		method: "POST",
+		path:   "files",
+		query:  url.Values{"fields": {"id,name"}},
+		body: &shortcut{
+			Name:            name,
+			MimeType:        ShortcutMimeType,
+			Parents:         []string{parentId},
+			ShortcutDetails: &shortcutDetails{TargetId: targetId},
+		},
+	}
+}
+
+// Returns a request moving the file to the trash
+func trashRequest(fileId string) *batchRequest {
+	return &batchRequest{
+		method: "PATCH",
+		path:   fmt.Sprintf("files/%s", url.PathEscape(fileId)),
+		query:  url.Values{"fields": {"id"}},
+		body:   map[string]bool{"trashed": true},
+	}
+}
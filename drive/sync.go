@@ -39,6 +39,9 @@ const (
 	KeepLocal
 	KeepRemote
 	KeepLargest
+	KeepNewest
+	KeepBoth
+	Interactive
 )
 
 func (self *Drive) prepareSyncFiles(localPath string, root *drive.File, cmp FileComparer) (*syncFiles, error) {
@@ -285,8 +288,9 @@ type LocalFile struct {
 }
 
 type RemoteFile struct {
-	relPath string
-	file    *drive.File
+	relPath    string
+	file       *drive.File
+	exportMime string
 }
 
 type changedFile struct {
@@ -295,16 +299,24 @@ type changedFile struct {
 }
 
 type syncFiles struct {
-	root    *RemoteFile
-	local   []*LocalFile
-	remote  []*RemoteFile
-	compare FileComparer
+	root     *RemoteFile
+	local    []*LocalFile
+	remote   []*RemoteFile
+	compare  FileComparer
+	exported ExportTracker
 }
 
 type FileComparer interface {
 	Changed(*LocalFile, *RemoteFile) bool
 }
 
+// ExportTracker keeps track of google documents that have been exported
+// by a sync download, so that unchanged documents are not exported again
+type ExportTracker interface {
+	Changed(*LocalFile, *RemoteFile) bool
+	Exported(absPath string, remote *RemoteFile)
+}
+
 func (self LocalFile) AbsPath() string {
 	return self.absPath
 }
@@ -330,6 +342,19 @@ func (self RemoteFile) Modified() time.Time {
 	return t
 }
 
+func (self RemoteFile) Id() string {
+	return self.file.Id
+}
+
+func (self RemoteFile) Version() int64 {
+	return self.file.Version
+}
+
+// Mime type the google document is exported as, empty for binary files
+func (self RemoteFile) ExportMime() string {
+	return self.exportMime
+}
+
 func (self *changedFile) compareModTime() ModTime {
 	localTime := self.local.Modified()
 	remoteTime := self.remote.Modified()
@@ -450,6 +475,17 @@ func (self *syncFiles) filterChangedRemoteFiles() []*changedFile {
 			continue
 		}
 
+		// Exported documents have no md5, ask the export tracker instead
+		if rf.exportMime != "" {
+			if self.exported == nil || self.exported.Changed(lf, rf) {
+				files = append(files, &changedFile{
+					local:  lf,
+					remote: rf,
+				})
+			}
+			continue
+		}
+
 		// Check if file has changed
 		if self.compare.Changed(lf, rf) {
 			files = append(files, &changedFile{
@@ -0,0 +1,155 @@
+package drive
+
+import (
+	"bufio"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+// What to do with a changed file where the destination is newer than the source
+type conflictAction int
+
+const (
+	// Overwrite the destination with the source
+	overwriteFile conflictAction = iota
+	// Leave the destination as is
+	skipFile
+	// Rename the destination and transfer the source
+	keepBothFiles
+)
+
+// Asks the user what to do with a conflicting file
+type conflictPrompter func(cf *changedFile) conflictAction
+
+// Returns a prompter that reads the answers from the given reader.
+// The upload flag tells which side is the destination of the sync
+func newConflictPrompter(in io.Reader, out io.Writer, upload bool) conflictPrompter {
+	if in == nil {
+		in = os.Stdin
+	}
+	reader := bufio.NewReader(in)
+
+	return func(cf *changedFile) conflictAction {
+		fmt.Fprintf(out, "\nConflict: %s\n", cf.local.relPath)
+		printConflictDiff(out, cf)
+
+		for {
+			fmt.Fprint(out, "Keep [l]ocal, [r]emote, [b]oth or [s]kip? ")
+
+			answer, err := reader.ReadString('\n')
+			if err != nil && answer == "" {
+				// Be non-destructive when there is nothing more to read
+				fmt.Fprintln(out)
+				return skipFile
+			}
+
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "l", "local":
+				if upload {
+					return overwriteFile
+				}
+				return skipFile
+			case "r", "remote":
+				if upload {
+					return skipFile
+				}
+				return overwriteFile
+			case "b", "both":
+				return keepBothFiles
+			case "s", "skip":
+				return skipFile
+			}
+		}
+	}
+}
+
+func printConflictDiff(out io.Writer, cf *changedFile) {
+	localMd5, err := fileMd5(cf.local.absPath)
+	if err != nil {
+		localMd5 = "?"
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "\tLocal\tRemote")
+	fmt.Fprintf(w, "Size\t%s\t%s\n", formatSize(cf.local.Size(), true), formatSize(cf.remote.Size(), true))
+	fmt.Fprintf(w, "Modified\t%s\t%s\n",
+		cf.local.Modified().Local().Format("Jan _2 2006 15:04:05.000"),
+		cf.remote.Modified().Local().Format("Jan _2 2006 15:04:05.000"),
+	)
+	fmt.Fprintf(w, "Md5\t%s\t%s\n", localMd5, cf.remote.Md5())
+
+	w.Flush()
+}
+
+func fileMd5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// Returns the name of a conflicting copy of the given file,
+// i.e. 'report (conflict 2026-10-17 host).xlsx'
+func conflictName(name string, t time.Time) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	return fmt.Sprintf("%s (conflict %s %s)%s", base, t.Format("2006-01-02"), host, ext)
+}
+
+// Renames the remote file and uploads the local file in its place
+func (self *Drive) keepBothRemote(cf *changedFile, args UploadSyncArgs) error {
+	if args.DryRun {
+		return nil
+	}
+
+	if len(cf.remote.file.Parents) == 0 {
+		return fmt.Errorf("Remote file %s does not have a parent", cf.remote.file.Id)
+	}
+
+	renamed := &drive.File{Name: conflictName(cf.remote.file.Name, time.Now())}
+	_, err := self.service.Files.Update(cf.remote.file.Id, renamed).Fields("id").Do()
+	if err != nil {
+		return fmt.Errorf("Failed to rename remote file: %s", err)
+	}
+
+	return self.uploadMissingFile(cf.remote.file.Parents[0], cf.local, args)
+}
+
+// Renames the local file and downloads the remote file in its place
+func (self *Drive) keepBothLocal(cf *changedFile, args DownloadSyncArgs) error {
+	if args.DryRun {
+		return nil
+	}
+
+	absPath := cf.local.absPath
+	renamed := filepath.Join(filepath.Dir(absPath), conflictName(filepath.Base(absPath), time.Now()))
+
+	if err := os.Rename(absPath, renamed); err != nil {
+		return fmt.Errorf("Failed to rename local file: %s", err)
+	}
+
+	return self.downloadRemoteFile(cf.remote, absPath, args)
+}
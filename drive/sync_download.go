@@ -3,9 +3,11 @@ package drive
 import (
 	"bytes"
 	"fmt"
+	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,6 +16,7 @@ import (
 
 type DownloadSyncArgs struct {
 	Out              io.Writer
+	In               io.Reader
 	Progress         io.Writer
 	RootId           string
 	Path             string
@@ -22,11 +25,32 @@ type DownloadSyncArgs struct {
 	Timeout          time.Duration
 	Resolution       ConflictResolution
 	Comparer         FileComparer
+	ExportDocs       bool
+	ExportFormats    []string
+	ExportTracker    ExportTracker
+	FollowShortcuts  bool
+	Plan             string
+	summary          *summary
+}
+
+func (args *DownloadSyncArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.RootId)
+	if err != nil {
+		return err
+	}
+
+	args.RootId = id
+	return nil
 }
 
 func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
 	fmt.Fprintln(args.Out, "Starting sync...")
 	started := time.Now()
+	args.summary = &summary{}
 
 	// Get remote root dir
 	rootDir, err := self.getSyncRoot(args.RootId)
@@ -40,11 +64,38 @@ func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
 		return err
 	}
 
+	// Add the targets of shortcuts, before documents so that
+	// documents in the target directories are exported too
+	if args.FollowShortcuts {
+		err = self.prepareRemoteShortcuts(files, args.Out)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add google documents that should be exported
+	if args.ExportDocs {
+		err = self.prepareRemoteDocs(files, args.ExportFormats)
+		if err != nil {
+			return err
+		}
+		files.exported = args.ExportTracker
+	}
+
 	// Find changed files
 	changedFiles := files.filterChangedRemoteFiles()
 
 	fmt.Fprintf(args.Out, "Found %d local files and %d remote files\n", len(files.local), len(files.remote))
 
+	// Write the intended changes to the plan file instead of syncing
+	if args.Plan != "" {
+		plan, err := newDownloadPlan(files, changedFiles, args)
+		if err != nil {
+			return err
+		}
+		return writeSyncPlan(args.Out, plan, args.Plan)
+	}
+
 	// Ensure that we don't overwrite any local changes
 	if args.Resolution == NoResolution {
 		err = ensureNoLocalModifications(changedFiles)
@@ -56,26 +107,26 @@ func (self *Drive) DownloadSync(args DownloadSyncArgs) error {
 	// Create missing directories
 	err = self.createMissingLocalDirs(files, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Download missing files
 	err = self.downloadMissingFiles(files, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Download files that has changed
 	err = self.downloadChangedFiles(changedFiles, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Delete extraneous local files
 	if args.DeleteExtraneous {
 		err = self.deleteExtraneousLocalFiles(files, args)
 		if err != nil {
-			return err
+			return self.abort(args.Out, args.summary, err)
 		}
 	}
 	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
@@ -110,11 +161,16 @@ func (self *Drive) createMissingLocalDirs(files *syncFiles, args DownloadSyncArg
 	if missingCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d local directories are missing\n", missingCount)
 	}
+	step := args.summary.step("Create local directories", missingCount)
 
 	// Sort directories so that the dirs with the shortest path comes first
 	sort.Sort(byRemotePathLength(missingDirs))
 
 	for i, rf := range missingDirs {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		absPath, err := filepath.Abs(filepath.Join(args.Path, rf.relPath))
 		if err != nil {
 			return fmt.Errorf("Failed to determine local absolute path: %s", err)
@@ -126,6 +182,7 @@ func (self *Drive) createMissingLocalDirs(files *syncFiles, args DownloadSyncArg
 		}
 
 		os.MkdirAll(absPath, 0775)
+		step.inc()
 	}
 
 	return nil
@@ -138,18 +195,24 @@ func (self *Drive) downloadMissingFiles(files *syncFiles, args DownloadSyncArgs)
 	if missingCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d local files are missing\n", missingCount)
 	}
+	step := args.summary.step("Download missing files", missingCount)
 
 	for i, rf := range missingFiles {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		absPath, err := filepath.Abs(filepath.Join(args.Path, rf.relPath))
 		if err != nil {
 			return fmt.Errorf("Failed to determine local absolute path: %s", err)
 		}
-		fmt.Fprintf(args.Out, "[%04d/%04d] Downloading %s -> %s\n", i+1, missingCount, rf.relPath, filepath.Join(filepath.Base(args.Path), rf.relPath))
+		fmt.Fprintf(args.Out, "[%04d/%04d] %s %s -> %s\n", i+1, missingCount, downloadAction(rf), rf.relPath, filepath.Join(filepath.Base(args.Path), rf.relPath))
 
-		err = self.downloadRemoteFile(rf.file.Id, absPath, args, 0)
+		err = self.downloadRemoteFile(rf, absPath, args)
 		if err != nil {
 			return err
 		}
+		step.inc()
 	}
 
 	return nil
@@ -161,10 +224,31 @@ func (self *Drive) downloadChangedFiles(changedFiles []*changedFile, args Downlo
 	if changedCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d remote files has changed\n", changedCount)
 	}
+	step := args.summary.step("Download changed files", changedCount)
+
+	var prompt conflictPrompter
+	if args.Resolution == Interactive {
+		prompt = newConflictPrompter(args.In, args.Out, false)
+	}
 
 	for i, cf := range changedFiles {
-		if skip, reason := checkLocalConflict(cf, args.Resolution); skip {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		action, reason := checkLocalConflict(cf, args.Resolution, prompt)
+		if action == skipFile {
 			fmt.Fprintf(args.Out, "[%04d/%04d] Skipping %s (%s)\n", i+1, changedCount, cf.remote.relPath, reason)
+			step.inc()
+			continue
+		}
+
+		if action == keepBothFiles {
+			fmt.Fprintf(args.Out, "[%04d/%04d] Keeping both %s (%s)\n", i+1, changedCount, cf.remote.relPath, reason)
+			if err := self.keepBothLocal(cf, args); err != nil {
+				return err
+			}
+			step.inc()
 			continue
 		}
 
@@ -172,32 +256,47 @@ func (self *Drive) downloadChangedFiles(changedFiles []*changedFile, args Downlo
 		if err != nil {
 			return fmt.Errorf("Failed to determine local absolute path: %s", err)
 		}
-		fmt.Fprintf(args.Out, "[%04d/%04d] Downloading %s -> %s\n", i+1, changedCount, cf.remote.relPath, filepath.Join(filepath.Base(args.Path), cf.remote.relPath))
+		fmt.Fprintf(args.Out, "[%04d/%04d] %s %s -> %s\n", i+1, changedCount, downloadAction(cf.remote), cf.remote.relPath, filepath.Join(filepath.Base(args.Path), cf.remote.relPath))
 
-		err = self.downloadRemoteFile(cf.remote.file.Id, absPath, args, 0)
+		err = self.downloadRemoteFile(cf.remote, absPath, args)
 		if err != nil {
 			return err
 		}
+		step.inc()
 	}
 
 	return nil
 }
 
-func (self *Drive) downloadRemoteFile(id, fpath string, args DownloadSyncArgs, try int) error {
+func (self *Drive) downloadRemoteFile(rf *RemoteFile, fpath string, args DownloadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
-	// Get timeout reader wrapper and context
-	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(args.Timeout)
+	err := self.retry(func(ctx context.Context) error {
+		return self.downloadRemoteFileOnce(ctx, rf, fpath, args)
+	})
+	if isRetryableError(err) {
+		return fmt.Errorf("Failed to download file: %s", err)
+	}
+	return err
+}
 
-	res, err := self.service.Files.Get(id).Context(ctx).Download()
+func (self *Drive) downloadRemoteFileOnce(ctx context.Context, rf *RemoteFile, fpath string, args DownloadSyncArgs) error {
+	// Get timeout reader wrapper and context
+	timeoutReaderWrapper, ctx := getTimeoutReaderWrapperContext(ctx, args.Timeout)
+
+	var res *http.Response
+	var err error
+	if rf.exportMime != "" {
+		res, err = self.service.Files.Export(rf.file.Id, rf.exportMime).Context(ctx).Download()
+	} else {
+		res, err = self.service.Files.Get(rf.file.Id).Context(ctx).Download()
+	}
 	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.downloadRemoteFile(id, fpath, args, try)
-		} else if isTimeoutError(err) {
+		if isRetryableError(err) {
+			return err
+		} else if self.isTimeoutError(err) {
 			return fmt.Errorf("Failed to download file: timeout, no data was transferred for %v", args.Timeout)
 		} else {
 			return fmt.Errorf("Failed to download file: %s", err)
@@ -231,21 +330,32 @@ func (self *Drive) downloadRemoteFile(id, fpath string, args DownloadSyncArgs, t
 	_, err = io.Copy(outFile, reader)
 	if err != nil {
 		outFile.Close()
-		if try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.downloadRemoteFile(id, fpath, args, try)
-		} else {
-			os.Remove(tmpPath)
-			return fmt.Errorf("Download was interrupted: %s", err)
-		}
+		os.Remove(tmpPath)
+		return &interruptedError{err}
 	}
 
 	// Close file
 	outFile.Close()
 
 	// Rename tmp file to proper filename
-	return os.Rename(tmpPath, fpath)
+	err = os.Rename(tmpPath, fpath)
+	if err != nil {
+		return err
+	}
+
+	// Remember which version of the document was exported
+	if rf.exportMime != "" && args.ExportTracker != nil {
+		args.ExportTracker.Exported(fpath, rf)
+	}
+
+	return nil
+}
+
+func downloadAction(rf *RemoteFile) string {
+	if rf.exportMime != "" {
+		return "Exporting"
+	}
+	return "Downloading"
 }
 
 func (self *Drive) deleteExtraneousLocalFiles(files *syncFiles, args DownloadSyncArgs) error {
@@ -255,11 +365,16 @@ func (self *Drive) deleteExtraneousLocalFiles(files *syncFiles, args DownloadSyn
 	if extraneousCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d local files are extraneous\n", extraneousCount)
 	}
+	step := args.summary.step("Delete extraneous local files", extraneousCount)
 
 	// Sort files so that the files with the longest path comes first
 	sort.Sort(sort.Reverse(byLocalPathLength(extraneousFiles)))
 
 	for i, lf := range extraneousFiles {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, extraneousCount, lf.absPath)
 
 		if args.DryRun {
@@ -270,50 +385,65 @@ func (self *Drive) deleteExtraneousLocalFiles(files *syncFiles, args DownloadSyn
 		if err != nil {
 			return fmt.Errorf("Failed to delete local file: %s", err)
 		}
+		step.inc()
 	}
 
 	return nil
 }
 
-func checkLocalConflict(cf *changedFile, resolution ConflictResolution) (bool, string) {
+// Decides what to do with a changed remote file, the returned reason
+// describes why the local file is skipped or kept
+func checkLocalConflict(cf *changedFile, resolution ConflictResolution, prompt conflictPrompter) (conflictAction, string) {
 	// No conflict unless local file was last modified
 	if cf.compareModTime() != LocalLastModified {
-		return false, ""
+		return overwriteFile, ""
 	}
 
-	// Don't skip if want to keep the remote file
-	if resolution == KeepRemote {
-		return false, ""
-	}
+	switch resolution {
+	case KeepRemote:
+		// Don't skip if want to keep the remote file
+		return overwriteFile, ""
 
-	// Skip if we want to keep the local file
-	if resolution == KeepLocal {
-		return true, "conflicting file, keeping local file"
-	}
+	case KeepLocal:
+		return skipFile, "conflicting file, keeping local file"
+
+	case KeepNewest:
+		// The local file is the newest one, since this is a conflict
+		return skipFile, "conflicting file, local file is newest, keeping local"
+
+	case KeepBoth:
+		return keepBothFiles, "conflicting file, renaming local file"
+
+	case Interactive:
+		action := prompt(cf)
+		if action == skipFile {
+			return skipFile, "conflicting file, keeping local file"
+		}
+		return action, "conflicting file, renaming local file"
 
-	if resolution == KeepLargest {
+	case KeepLargest:
 		largest := cf.compareSize()
 
 		// Skip if the local file is largest
 		if largest == LocalLargestSize {
-			return true, "conflicting file, local file is largest, keeping local"
+			return skipFile, "conflicting file, local file is largest, keeping local"
 		}
 
 		// Don't skip if the remote file is largest
 		if largest == RemoteLargestSize {
-			return false, ""
+			return overwriteFile, ""
 		}
 
 		// Keep local if both files have the same size
 		if largest == EqualSize {
-			return true, "conflicting file, file sizes are equal, keeping local"
+			return skipFile, "conflicting file, file sizes are equal, keeping local"
 		}
 	}
 
 	// The conditionals above should cover all cases,
 	// unless the programmer did something wrong,
 	// in which case we default to being non-destructive and skip the file
-	return true, "conflicting file, unhandled case"
+	return skipFile, "conflicting file, unhandled case"
 }
 
 func ensureNoLocalModifications(files []*changedFile) error {
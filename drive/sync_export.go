@@ -0,0 +1,107 @@
+package drive
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Max number of parent conditions in a single query
+const MaxQueryParents = 50
+
+// Google document types that are exported by sync download
+var syncExportMimes = []string{
+	"application/vnd.google-apps.document",
+	"application/vnd.google-apps.spreadsheet",
+	"application/vnd.google-apps.presentation",
+}
+
+// Finds all google documents in the directories of the sync tree and adds
+// them as remote files, with the path of the exported file as relative path.
+// Documents are created by other applications and are thus not tagged with
+// the syncRootId app property, so we have to look them up by parent
+func (self *Drive) prepareRemoteDocs(files *syncFiles, formats []string) error {
+	exportMimes, err := parseExportFormats(formats)
+	if err != nil {
+		return err
+	}
+
+	// Map of directory id -> relative path
+	dirs := map[string]string{files.root.file.Id: ""}
+	for _, rf := range files.remote {
+		if isDir(rf.file) {
+			dirs[rf.file.Id] = rf.relPath
+		}
+	}
+
+	var dirIds []string
+	for id := range dirs {
+		dirIds = append(dirIds, id)
+	}
+
+	// Keep track of used paths to detect name collisions
+	paths := map[string]string{}
+	for _, rf := range files.remote {
+		paths[rf.relPath] = rf.file.Id
+	}
+
+	var mimeConditions []string
+	for _, m := range syncExportMimes {
+		mimeConditions = append(mimeConditions, fmt.Sprintf("mimeType = '%s'", m))
+	}
+
+	for i := 0; i < len(dirIds); i += MaxQueryParents {
+		chunk := dirIds[i:min(i+MaxQueryParents, len(dirIds))]
+
+		var parentConditions []string
+		for _, id := range chunk {
+			parentConditions = append(parentConditions, fmt.Sprintf("'%s' in parents", id))
+		}
+
+		listArgs := listAllFilesArgs{
+			query:  fmt.Sprintf("trashed = false and (%s) and (%s)", strings.Join(mimeConditions, " or "), strings.Join(parentConditions, " or ")),
+			fields: []googleapi.Field{"nextPageToken", "files(id,name,parents,mimeType,modifiedTime,version)"},
+		}
+		docs, err := self.listAllFiles(listArgs)
+		if err != nil {
+			return fmt.Errorf("Failed listing documents: %s", err)
+		}
+
+		for _, f := range docs {
+			dirPath, ok := findDirPath(dirs, f.Parents)
+			if !ok {
+				return fmt.Errorf("Document %s does not have a valid parent", f.Id)
+			}
+
+			exportMime, err := getExportMime(exportMimes[f.MimeType], f.MimeType)
+			if err != nil {
+				return err
+			}
+
+			relPath := filepath.Join(dirPath, getExportFilename(f.Name, exportMime))
+			if dupeId, isDupe := paths[relPath]; isDupe {
+				return fmt.Errorf("Found name collision between %s and %s", f.Id, dupeId)
+			}
+			paths[relPath] = f.Id
+
+			files.remote = append(files.remote, &RemoteFile{
+				relPath:    relPath,
+				file:       f,
+				exportMime: exportMime,
+			})
+		}
+	}
+
+	return nil
+}
+
+func findDirPath(dirs map[string]string, parents []string) (string, bool) {
+	for _, parent := range parents {
+		if path, ok := dirs[parent]; ok {
+			return path, true
+		}
+	}
+	return "", false
+}
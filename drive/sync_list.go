@@ -36,7 +36,21 @@ type ListRecursiveSyncArgs struct {
 	SortOrder   string
 }
 
+func (args *ListRecursiveSyncArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.RootId)
+	if err != nil {
+		return err
+	}
+
+	args.RootId = id
+	return nil
+}
+
 func (self *Drive) ListRecursiveSync(args ListRecursiveSyncArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
 	rootDir, err := self.getSyncRoot(args.RootId)
 	if err != nil {
 		return err
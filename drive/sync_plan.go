@@ -0,0 +1,551 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/api/drive/v3"
+)
+
+const SyncPlanVersion = 1
+
+const (
+	PlanUpload   = "upload"
+	PlanDownload = "download"
+)
+
+const (
+	PlanMkdir  = "mkdir"
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanDelete = "delete"
+	// Rename the destination file and transfer the source file
+	PlanKeepBoth = "keep-both"
+)
+
+// A sync plan is a snapshot of the actions a sync would perform, along
+// with the state of the files at the time the plan was made. The plan
+// is only applied if the files are still in the same state
+type SyncPlan struct {
+	Version       int             `json:"version"`
+	Direction     string          `json:"direction"`
+	RootId        string          `json:"rootId"`
+	Path          string          `json:"path"`
+	Created       string          `json:"created"`
+	ExportDocs    bool            `json:"exportDocs,omitempty"`
+	ExportFormats []string        `json:"exportFormats,omitempty"`
+	Actions       []*PlanAction   `json:"actions"`
+	Conflicts     []*PlanConflict `json:"conflicts"`
+}
+
+// Local and Remote holds the expected state of the file, a nil
+// state means that the file is expected to not exist
+type PlanAction struct {
+	Action string      `json:"action"`
+	Path   string      `json:"path"`
+	Local  *PlanLocal  `json:"local"`
+	Remote *PlanRemote `json:"remote"`
+}
+
+type PlanConflict struct {
+	Path   string      `json:"path"`
+	Reason string      `json:"reason"`
+	Local  *PlanLocal  `json:"local"`
+	Remote *PlanRemote `json:"remote"`
+}
+
+type PlanLocal struct {
+	IsDir    bool   `json:"isDir,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Modified string `json:"modified,omitempty"`
+}
+
+type PlanRemote struct {
+	Id         string `json:"id"`
+	IsDir      bool   `json:"isDir,omitempty"`
+	Md5        string `json:"md5,omitempty"`
+	Size       int64  `json:"size,omitempty"`
+	Modified   string `json:"modified,omitempty"`
+	ExportMime string `json:"exportMime,omitempty"`
+}
+
+func newPlanLocal(lf *LocalFile) *PlanLocal {
+	if lf == nil {
+		return nil
+	}
+
+	// The modification time of directories changes with their content
+	if lf.info.IsDir() {
+		return &PlanLocal{IsDir: true}
+	}
+
+	return &PlanLocal{
+		Size:     lf.Size(),
+		Modified: lf.Modified().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func newPlanRemote(rf *RemoteFile) *PlanRemote {
+	if rf == nil {
+		return nil
+	}
+
+	if isDir(rf.file) {
+		return &PlanRemote{Id: rf.file.Id, IsDir: true}
+	}
+
+	return &PlanRemote{
+		Id:         rf.file.Id,
+		Md5:        rf.file.Md5Checksum,
+		Size:       rf.file.Size,
+		Modified:   rf.file.ModifiedTime,
+		ExportMime: rf.exportMime,
+	}
+}
+
+func (self *PlanLocal) matches(lf *LocalFile) bool {
+	if self == nil || lf == nil {
+		return self == nil && lf == nil
+	}
+	return *self == *newPlanLocal(lf)
+}
+
+func (self *PlanRemote) matches(rf *RemoteFile) bool {
+	if self == nil || rf == nil {
+		return self == nil && rf == nil
+	}
+	return *self == *newPlanRemote(rf)
+}
+
+func (self *SyncPlan) add(action, relPath string, lf *LocalFile, rf *RemoteFile) {
+	self.Actions = append(self.Actions, &PlanAction{
+		Action: action,
+		Path:   relPath,
+		Local:  newPlanLocal(lf),
+		Remote: newPlanRemote(rf),
+	})
+}
+
+func (self *SyncPlan) addConflict(cf *changedFile, reason string) {
+	self.Conflicts = append(self.Conflicts, &PlanConflict{
+		Path:   cf.local.relPath,
+		Reason: reason,
+		Local:  newPlanLocal(cf.local),
+		Remote: newPlanRemote(cf.remote),
+	})
+}
+
+func (self *SyncPlan) count(action string) int {
+	var n int
+	for _, a := range self.Actions {
+		if a.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+func newSyncPlan(direction, rootId, path string, resolution ConflictResolution) (*SyncPlan, error) {
+	if resolution == Interactive {
+		return nil, fmt.Errorf("Interactive conflict resolution can not be used when creating a plan")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to determine local absolute path: %s", err)
+	}
+
+	return &SyncPlan{
+		Version:   SyncPlanVersion,
+		Direction: direction,
+		RootId:    rootId,
+		Path:      absPath,
+		Created:   time.Now().Format(time.RFC3339),
+		Actions:   []*PlanAction{},
+		Conflicts: []*PlanConflict{},
+	}, nil
+}
+
+// Creates the plan of an upload sync, changed files that would be
+// skipped because of a conflict are added as conflicts
+func newUploadPlan(files *syncFiles, changedFiles []*changedFile, args UploadSyncArgs) (*SyncPlan, error) {
+	plan, err := newSyncPlan(PlanUpload, args.RootId, args.Path, args.Resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	missingDirs := files.filterMissingRemoteDirs()
+	sort.Sort(byLocalPathLength(missingDirs))
+	for _, lf := range missingDirs {
+		plan.add(PlanMkdir, lf.relPath, lf, nil)
+	}
+
+	for _, lf := range files.filterMissingRemoteFiles() {
+		plan.add(PlanCreate, lf.relPath, lf, nil)
+	}
+
+	for _, cf := range changedFiles {
+		if args.Resolution == NoResolution && cf.compareModTime() == RemoteLastModified {
+			plan.addConflict(cf, "remote file is newer and no conflict resolution was given")
+			continue
+		}
+
+		switch action, reason := checkRemoteConflict(cf, args.Resolution, nil); action {
+		case skipFile:
+			plan.addConflict(cf, reason)
+		case keepBothFiles:
+			plan.add(PlanKeepBoth, cf.local.relPath, cf.local, cf.remote)
+		default:
+			plan.add(PlanUpdate, cf.local.relPath, cf.local, cf.remote)
+		}
+	}
+
+	if args.DeleteExtraneous {
+		extraneousFiles := files.filterExtraneousRemoteFiles()
+		sort.Sort(sort.Reverse(byRemotePathLength(extraneousFiles)))
+		for _, rf := range extraneousFiles {
+			plan.add(PlanDelete, rf.relPath, nil, rf)
+		}
+	}
+
+	return plan, nil
+}
+
+// Creates the plan of a download sync, changed files that would be
+// skipped because of a conflict are added as conflicts
+func newDownloadPlan(files *syncFiles, changedFiles []*changedFile, args DownloadSyncArgs) (*SyncPlan, error) {
+	plan, err := newSyncPlan(PlanDownload, args.RootId, args.Path, args.Resolution)
+	if err != nil {
+		return nil, err
+	}
+	plan.ExportDocs = args.ExportDocs
+	plan.ExportFormats = args.ExportFormats
+
+	missingDirs := files.filterMissingLocalDirs()
+	sort.Sort(byRemotePathLength(missingDirs))
+	for _, rf := range missingDirs {
+		plan.add(PlanMkdir, rf.relPath, nil, rf)
+	}
+
+	for _, rf := range files.filterMissingLocalFiles() {
+		plan.add(PlanCreate, rf.relPath, nil, rf)
+	}
+
+	for _, cf := range changedFiles {
+		if args.Resolution == NoResolution && cf.compareModTime() == LocalLastModified {
+			plan.addConflict(cf, "local file is newer and no conflict resolution was given")
+			continue
+		}
+
+		switch action, reason := checkLocalConflict(cf, args.Resolution, nil); action {
+		case skipFile:
+			plan.addConflict(cf, reason)
+		case keepBothFiles:
+			plan.add(PlanKeepBoth, cf.remote.relPath, cf.local, cf.remote)
+		default:
+			plan.add(PlanUpdate, cf.remote.relPath, cf.local, cf.remote)
+		}
+	}
+
+	if args.DeleteExtraneous {
+		extraneousFiles := files.filterExtraneousLocalFiles()
+		sort.Sort(sort.Reverse(byLocalPathLength(extraneousFiles)))
+		for _, lf := range extraneousFiles {
+			plan.add(PlanDelete, lf.relPath, lf, nil)
+		}
+	}
+
+	return plan, nil
+}
+
+func writeSyncPlan(out io.Writer, plan *SyncPlan, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Failed to write plan: %s", err)
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(plan)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to write plan: %s", err)
+	}
+
+	printSyncPlan(out, plan)
+	fmt.Fprintf(out, "\nPlan written to %s\n", path)
+	return nil
+}
+
+func readSyncPlan(path string) (*SyncPlan, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read plan: %s", err)
+	}
+	defer f.Close()
+
+	plan := &SyncPlan{}
+	if err := json.NewDecoder(f).Decode(plan); err != nil {
+		return nil, fmt.Errorf("Failed to read plan: %s", err)
+	}
+
+	if plan.Version != SyncPlanVersion {
+		return nil, fmt.Errorf("Unsupported plan version %d", plan.Version)
+	}
+
+	if plan.Direction != PlanUpload && plan.Direction != PlanDownload {
+		return nil, fmt.Errorf("Unknown plan direction '%s'", plan.Direction)
+	}
+
+	return plan, nil
+}
+
+func printSyncPlan(out io.Writer, plan *SyncPlan) {
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 0, 3, ' ', 0)
+
+	fmt.Fprintln(w, "Action\tPath")
+	for _, a := range plan.Actions {
+		fmt.Fprintf(w, "%s\t%s\n", a.Action, a.Path)
+	}
+	for _, c := range plan.Conflicts {
+		fmt.Fprintf(w, "conflict\t%s (%s)\n", c.Path, c.Reason)
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n%d directories to create, %d files to create, %d files to update, %d files to keep both of, %d files to delete, %d conflicts\n",
+		plan.count(PlanMkdir),
+		plan.count(PlanCreate),
+		plan.count(PlanUpdate),
+		plan.count(PlanKeepBoth),
+		plan.count(PlanDelete),
+		len(plan.Conflicts),
+	)
+}
+
+type ApplySyncPlanArgs struct {
+	Out           io.Writer
+	Progress      io.Writer
+	PlanPath      string
+	ChunkSize     int64
+	Timeout       time.Duration
+	Comparer      FileComparer
+	ExportTracker ExportTracker
+	SkipConflicts bool
+}
+
+// Applies the actions of a sync plan, after verifying that
+// no files have changed since the plan was made. Plans with
+// conflicts are only applied when skipping them is accepted
+func (self *Drive) ApplySyncPlan(args ApplySyncPlanArgs) error {
+	plan, err := readSyncPlan(args.PlanPath)
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Conflicts) > 0 && !args.SkipConflicts {
+		return fmt.Errorf("Plan has %d unresolved conflicts, use --skip-conflicts to apply it without syncing the conflicting files", len(plan.Conflicts))
+	}
+
+	if args.ChunkSize > intMax()-1 {
+		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
+	}
+
+	fmt.Fprintln(args.Out, "Starting sync...")
+	started := time.Now()
+
+	var rootDir *drive.File
+	if plan.Direction == PlanUpload {
+		rootDir, err = self.prepareSyncRoot(UploadSyncArgs{RootId: plan.RootId})
+	} else {
+		rootDir, err = self.getSyncRoot(plan.RootId)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(args.Out, "Verifying plan against current file information...")
+	files, err := self.prepareSyncFiles(plan.Path, rootDir, args.Comparer)
+	if err != nil {
+		return err
+	}
+
+	if plan.ExportDocs {
+		err = self.prepareRemoteDocs(files, plan.ExportFormats)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := verifySyncPlan(plan, files); err != nil {
+		return err
+	}
+
+	s := &summary{}
+	if plan.Direction == PlanUpload {
+		err = self.applyUploadPlan(plan, files, s, UploadSyncArgs{
+			Out:       args.Out,
+			Progress:  args.Progress,
+			Path:      plan.Path,
+			RootId:    rootDir.Id,
+			ChunkSize: args.ChunkSize,
+			Timeout:   args.Timeout,
+		})
+	} else {
+		err = self.applyDownloadPlan(plan, files, s, DownloadSyncArgs{
+			Out:           args.Out,
+			Progress:      args.Progress,
+			Path:          plan.Path,
+			RootId:        rootDir.Id,
+			Timeout:       args.Timeout,
+			ExportTracker: args.ExportTracker,
+		})
+	}
+	if err != nil {
+		return self.abort(args.Out, s, err)
+	}
+
+	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
+	return nil
+}
+
+// Ensures that all files are in the same state as when the plan was made
+func verifySyncPlan(plan *SyncPlan, files *syncFiles) error {
+	var changed []string
+
+	for _, a := range plan.Actions {
+		lf, _ := files.findLocalByPath(a.Path)
+		rf, _ := files.findRemoteByPath(a.Path)
+
+		if !a.Local.matches(lf) || !a.Remote.matches(rf) {
+			changed = append(changed, a.Path)
+		}
+	}
+
+	if len(changed) > 0 {
+		return fmt.Errorf("The following files have changed since the plan was created, create a new plan:\n%s", strings.Join(changed, "\n"))
+	}
+
+	return nil
+}
+
+func (self *Drive) applyUploadPlan(plan *SyncPlan, files *syncFiles, s *summary, args UploadSyncArgs) error {
+	total := len(plan.Actions)
+	step := s.step("Apply upload plan", total)
+
+	for i, a := range plan.Actions {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		lf, _ := files.findLocalByPath(a.Path)
+		rf, _ := files.findRemoteByPath(a.Path)
+
+		if a.Action == PlanDelete {
+			fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, total, filepath.Join(files.root.file.Name, a.Path))
+			if err := self.deleteRemoteFile(rf, args); err != nil {
+				return err
+			}
+			step.inc()
+			continue
+		}
+
+		parentPath := parentFilePath(a.Path)
+		parent, ok := files.findRemoteByPath(parentPath)
+		if !ok {
+			return fmt.Errorf("Could not find remote directory with path '%s'", parentPath)
+		}
+
+		switch a.Action {
+		case PlanMkdir:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Creating directory %s\n", i+1, total, filepath.Join(files.root.file.Name, a.Path))
+			f, err := self.createMissingRemoteDir(createMissingRemoteDirArgs{
+				name:     lf.info.Name(),
+				parentId: parent.file.Id,
+				rootId:   args.RootId,
+			})
+			if err != nil {
+				return err
+			}
+			files.remote = append(files.remote, &RemoteFile{relPath: a.Path, file: f})
+
+		case PlanCreate:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Uploading %s -> %s\n", i+1, total, a.Path, filepath.Join(files.root.file.Name, a.Path))
+			if err := self.uploadMissingFile(parent.file.Id, lf, args); err != nil {
+				return err
+			}
+
+		case PlanUpdate:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Updating %s -> %s\n", i+1, total, a.Path, filepath.Join(files.root.file.Name, a.Path))
+			if err := self.updateChangedFile(&changedFile{local: lf, remote: rf}, args); err != nil {
+				return err
+			}
+
+		case PlanKeepBoth:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Keeping both %s\n", i+1, total, a.Path)
+			if err := self.keepBothRemote(&changedFile{local: lf, remote: rf}, args); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("Unknown plan action '%s'", a.Action)
+		}
+		step.inc()
+	}
+
+	return nil
+}
+
+func (self *Drive) applyDownloadPlan(plan *SyncPlan, files *syncFiles, s *summary, args DownloadSyncArgs) error {
+	total := len(plan.Actions)
+	step := s.step("Apply download plan", total)
+
+	for i, a := range plan.Actions {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		absPath := filepath.Join(plan.Path, a.Path)
+		rf, _ := files.findRemoteByPath(a.Path)
+
+		switch a.Action {
+		case PlanMkdir:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Creating directory %s\n", i+1, total, filepath.Join(filepath.Base(plan.Path), a.Path))
+			if err := os.MkdirAll(absPath, 0775); err != nil {
+				return fmt.Errorf("Failed to create directory: %s", err)
+			}
+
+		case PlanCreate, PlanUpdate:
+			fmt.Fprintf(args.Out, "[%04d/%04d] %s %s -> %s\n", i+1, total, downloadAction(rf), a.Path, filepath.Join(filepath.Base(plan.Path), a.Path))
+			if err := self.downloadRemoteFile(rf, absPath, args); err != nil {
+				return err
+			}
+
+		case PlanKeepBoth:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Keeping both %s\n", i+1, total, a.Path)
+			lf, _ := files.findLocalByPath(a.Path)
+			if err := self.keepBothLocal(&changedFile{local: lf, remote: rf}, args); err != nil {
+				return err
+			}
+
+		case PlanDelete:
+			fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, total, absPath)
+			if err := os.Remove(absPath); err != nil {
+				return fmt.Errorf("Failed to delete local file: %s", err)
+			}
+
+		default:
+			return fmt.Errorf("Unknown plan action '%s'", a.Action)
+		}
+		step.inc()
+	}
+
+	return nil
+}
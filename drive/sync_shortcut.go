@@ -0,0 +1,163 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Looks up shortcuts in the synced directories and adds their targets as
+// remote files, with the path of the shortcut as relative path. Shortcuts
+// to directories add the whole directory tree, shortcuts inside it are not
+// followed. Like documents, shortcuts are not tagged with the syncRootId
+// app property, so we have to look them up by parent. Shortcuts with a
+// missing target and shortcuts that would create a cycle are skipped
+func (self *Drive) prepareRemoteShortcuts(files *syncFiles, out io.Writer) error {
+	// Map of directory id -> relative path
+	dirs := map[string]string{files.root.file.Id: ""}
+	for _, rf := range files.remote {
+		if isDir(rf.file) {
+			dirs[rf.file.Id] = rf.relPath
+		}
+	}
+
+	var dirIds []string
+	for id := range dirs {
+		dirIds = append(dirIds, id)
+	}
+
+	// Keep track of used paths to detect name collisions
+	paths := map[string]string{}
+	for _, rf := range files.remote {
+		paths[rf.relPath] = rf.file.Id
+	}
+
+	fields := []googleapi.Field{"id", "name", "parents", "md5Checksum", "mimeType", "size", "modifiedTime"}
+
+	// Ancestors of the root, looked up for the first shortcut to a directory
+	var rootAncestors map[string]bool
+
+	for i := 0; i < len(dirIds); i += MaxQueryParents {
+		chunk := dirIds[i:min(i+MaxQueryParents, len(dirIds))]
+
+		var parentConditions []string
+		for _, id := range chunk {
+			parentConditions = append(parentConditions, fmt.Sprintf("'%s' in parents", id))
+		}
+
+		listArgs := listAllFilesArgs{
+			query:  fmt.Sprintf("trashed = false and mimeType = '%s' and (%s)", ShortcutMimeType, strings.Join(parentConditions, " or ")),
+			fields: []googleapi.Field{"nextPageToken", "files(id,name,parents,mimeType)"},
+		}
+		shortcuts, err := self.listAllFiles(listArgs)
+		if err != nil {
+			return fmt.Errorf("Failed listing shortcuts: %s", err)
+		}
+
+		targets, errors := self.shortcutTargets(shortcuts, fields...)
+
+		for j, s := range shortcuts {
+			dirPath, ok := findDirPath(dirs, s.Parents)
+			if !ok {
+				return fmt.Errorf("Shortcut %s does not have a valid parent", s.Id)
+			}
+			relPath := filepath.Join(dirPath, s.Name)
+
+			if errors[j] != nil {
+				fmt.Fprintf(out, "Skipping shortcut %s: %s\n", relPath, errors[j])
+				continue
+			}
+
+			target := targets[j]
+			if !isDir(target) && !isBinary(target) {
+				fmt.Fprintf(out, "Skipping shortcut %s to google document\n", relPath)
+				continue
+			}
+
+			tree := []*treeFile{{file: target}}
+			if isDir(target) {
+				if rootAncestors == nil {
+					rootAncestors, err = self.ancestorIds(files.root.file.Id)
+					if err != nil {
+						return err
+					}
+				}
+
+				// The target tree would contain a synced directory, which
+				// is already included, if the target is one of them or an
+				// ancestor of the root. Checked before listing the tree
+				_, synced := dirs[target.Id]
+				if synced || rootAncestors[target.Id] {
+					fmt.Fprintf(out, "Skipping shortcut %s, shortcut cycle detected\n", relPath)
+					continue
+				}
+
+				tree, err = self.fileTree([]string{target.Id}, "id,name,parents,md5Checksum,mimeType,size,modifiedTime")
+				if err != nil {
+					return err
+				}
+			}
+
+			relPaths := map[*treeFile]string{}
+			for _, tf := range tree {
+				f := tf.file
+				if tf.parent == nil {
+					// The target is stored under the name of the shortcut
+					f = renamed(f, s.Name)
+					relPaths[tf] = relPath
+				} else {
+					relPaths[tf] = filepath.Join(relPaths[tf.parent], f.Name)
+				}
+
+				if !isDir(f) && !isBinary(f) {
+					continue
+				}
+
+				if dupeId, isDupe := paths[relPaths[tf]]; isDupe {
+					return fmt.Errorf("Found name collision between %s and %s", f.Id, dupeId)
+				}
+				paths[relPaths[tf]] = f.Id
+
+				files.remote = append(files.remote, &RemoteFile{
+					relPath: relPaths[tf],
+					file:    f,
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the ids of the ancestors of the file, the walk ends at
+// the first ancestor that is not accessible, i.e. of a shared folder
+func (self *Drive) ancestorIds(id string) (map[string]bool, error) {
+	ancestors := map[string]bool{}
+	for {
+		f, err := self.service.Files.Get(id).Fields("id", "parents").Do()
+		if err != nil {
+			if len(ancestors) > 0 {
+				return ancestors, nil
+			}
+			return nil, fmt.Errorf("Failed to get file: %s", err)
+		}
+
+		if len(f.Parents) == 0 || ancestors[f.Parents[0]] {
+			return ancestors, nil
+		}
+
+		id = f.Parents[0]
+		ancestors[id] = true
+	}
+}
+
+// Returns a copy of the file with the given name
+func renamed(f *drive.File, name string) *drive.File {
+	c := *f
+	c.Name = name
+	return &c
+}
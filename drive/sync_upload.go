@@ -9,12 +9,14 @@ import (
 	"sort"
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
 
 type UploadSyncArgs struct {
 	Out              io.Writer
+	In               io.Reader
 	Progress         io.Writer
 	Path             string
 	RootId           string
@@ -24,15 +26,32 @@ type UploadSyncArgs struct {
 	Timeout          time.Duration
 	Resolution       ConflictResolution
 	Comparer         FileComparer
+	Plan             string
+	summary          *summary
+}
+
+func (args *UploadSyncArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.RootId)
+	if err != nil {
+		return err
+	}
+
+	args.RootId = id
+	return nil
 }
 
 func (self *Drive) UploadSync(args UploadSyncArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
 	}
 
 	fmt.Fprintln(args.Out, "Starting sync...")
 	started := time.Now()
+	args.summary = &summary{}
 
 	// Create root directory if it does not exist
 	rootDir, err := self.prepareSyncRoot(args)
@@ -52,6 +71,15 @@ func (self *Drive) UploadSync(args UploadSyncArgs) error {
 
 	fmt.Fprintf(args.Out, "Found %d local files and %d remote files\n", len(files.local), len(files.remote))
 
+	// Write the intended changes to the plan file instead of syncing
+	if args.Plan != "" {
+		plan, err := newUploadPlan(files, changedFiles, args)
+		if err != nil {
+			return err
+		}
+		return writeSyncPlan(args.Out, plan, args.Plan)
+	}
+
 	// Ensure that there is enough free space on drive
 	if ok, msg := self.checkRemoteFreeSpace(missingFiles, changedFiles); !ok {
 		return fmt.Errorf(msg)
@@ -68,26 +96,26 @@ func (self *Drive) UploadSync(args UploadSyncArgs) error {
 	// Create missing directories
 	files, err = self.createMissingRemoteDirs(files, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Upload missing files
 	err = self.uploadMissingFiles(missingFiles, files, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Update modified files
 	err = self.updateChangedFiles(changedFiles, rootDir, args)
 	if err != nil {
-		return err
+		return self.abort(args.Out, args.summary, err)
 	}
 
 	// Delete extraneous files on drive
 	if args.DeleteExtraneous {
 		err = self.deleteExtraneousRemoteFiles(files, args)
 		if err != nil {
-			return err
+			return self.abort(args.Out, args.summary, err)
 		}
 	}
 	fmt.Fprintf(args.Out, "Sync finished in %s\n", time.Since(started))
@@ -124,6 +152,11 @@ func (self *Drive) prepareSyncRoot(args UploadSyncArgs) (*drive.File, error) {
 		return nil, fmt.Errorf("Root directory is not empty, the initial sync requires an empty directory")
 	}
 
+	// Leave the directory untouched when only making a plan
+	if args.Plan != "" {
+		return f, nil
+	}
+
 	// Update directory with syncRoot property
 	dstFile := &drive.File{
 		AppProperties: map[string]string{"sync": "true", "syncRoot": "true"},
@@ -144,11 +177,16 @@ func (self *Drive) createMissingRemoteDirs(files *syncFiles, args UploadSyncArgs
 	if missingCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d remote directories are missing\n", missingCount)
 	}
+	step := args.summary.step("Create remote directories", missingCount)
 
 	// Sort directories so that the dirs with the shortest path comes first
 	sort.Sort(byLocalPathLength(missingDirs))
 
 	for i, lf := range missingDirs {
+		if self.interrupted() {
+			return nil, ErrInterrupted
+		}
+
 		parentPath := parentFilePath(lf.relPath)
 		parent, ok := files.findRemoteByPath(parentPath)
 		if !ok {
@@ -162,7 +200,6 @@ func (self *Drive) createMissingRemoteDirs(files *syncFiles, args UploadSyncArgs
 			parentId: parent.file.Id,
 			rootId:   args.RootId,
 			dryRun:   args.DryRun,
-			try:      0,
 		})
 		if err != nil {
 			return nil, err
@@ -172,6 +209,7 @@ func (self *Drive) createMissingRemoteDirs(files *syncFiles, args UploadSyncArgs
 			relPath: lf.relPath,
 			file:    f,
 		})
+		step.inc()
 	}
 
 	return files, nil
@@ -182,7 +220,6 @@ type createMissingRemoteDirArgs struct {
 	parentId string
 	rootId   string
 	dryRun   bool
-	try      int
 }
 
 func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFiles, args UploadSyncArgs) error {
@@ -191,8 +228,13 @@ func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFile
 	if missingCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d remote files are missing\n", missingCount)
 	}
+	step := args.summary.step("Upload missing files", missingCount)
 
 	for i, lf := range missingFiles {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		parentPath := parentFilePath(lf.relPath)
 		parent, ok := files.findRemoteByPath(parentPath)
 		if !ok {
@@ -201,10 +243,11 @@ func (self *Drive) uploadMissingFiles(missingFiles []*LocalFile, files *syncFile
 
 		fmt.Fprintf(args.Out, "[%04d/%04d] Uploading %s -> %s\n", i+1, missingCount, lf.relPath, filepath.Join(files.root.file.Name, lf.relPath))
 
-		err := self.uploadMissingFile(parent.file.Id, lf, args, 0)
+		err := self.uploadMissingFile(parent.file.Id, lf, args)
 		if err != nil {
 			return err
 		}
+		step.inc()
 	}
 
 	return nil
@@ -216,19 +259,41 @@ func (self *Drive) updateChangedFiles(changedFiles []*changedFile, root *drive.F
 	if changedCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d local files has changed\n", changedCount)
 	}
+	step := args.summary.step("Update changed files", changedCount)
+
+	var prompt conflictPrompter
+	if args.Resolution == Interactive {
+		prompt = newConflictPrompter(args.In, args.Out, true)
+	}
 
 	for i, cf := range changedFiles {
-		if skip, reason := checkRemoteConflict(cf, args.Resolution); skip {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
+		action, reason := checkRemoteConflict(cf, args.Resolution, prompt)
+		if action == skipFile {
 			fmt.Fprintf(args.Out, "[%04d/%04d] Skipping %s (%s)\n", i+1, changedCount, cf.local.relPath, reason)
+			step.inc()
+			continue
+		}
+
+		if action == keepBothFiles {
+			fmt.Fprintf(args.Out, "[%04d/%04d] Keeping both %s (%s)\n", i+1, changedCount, cf.local.relPath, reason)
+			if err := self.keepBothRemote(cf, args); err != nil {
+				return err
+			}
+			step.inc()
 			continue
 		}
 
 		fmt.Fprintf(args.Out, "[%04d/%04d] Updating %s -> %s\n", i+1, changedCount, cf.local.relPath, filepath.Join(root.Name, cf.local.relPath))
 
-		err := self.updateChangedFile(cf, args, 0)
+		err := self.updateChangedFile(cf, args)
 		if err != nil {
 			return err
 		}
+		step.inc()
 	}
 
 	return nil
@@ -241,17 +306,23 @@ func (self *Drive) deleteExtraneousRemoteFiles(files *syncFiles, args UploadSync
 	if extraneousCount > 0 {
 		fmt.Fprintf(args.Out, "\n%d remote files are extraneous\n", extraneousCount)
 	}
+	step := args.summary.step("Delete extraneous remote files", extraneousCount)
 
 	// Sort files so that the files with the longest path comes first
 	sort.Sort(sort.Reverse(byRemotePathLength(extraneousFiles)))
 
 	for i, rf := range extraneousFiles {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		fmt.Fprintf(args.Out, "[%04d/%04d] Deleting %s\n", i+1, extraneousCount, filepath.Join(files.root.file.Name, rf.relPath))
 
-		err := self.deleteRemoteFile(rf, args, 0)
+		err := self.deleteRemoteFile(rf, args)
 		if err != nil {
 			return err
 		}
+		step.inc()
 	}
 
 	return nil
@@ -271,23 +342,27 @@ func (self *Drive) createMissingRemoteDir(args createMissingRemoteDirArgs) (*dri
 
 	f, err := self.service.Files.Create(dstFile).Do()
 	if err != nil {
-		if isBackendOrRateLimitError(err) && args.try < MaxErrorRetries {
-			exponentialBackoffSleep(args.try)
-			args.try++
-			return self.createMissingRemoteDir(args)
-		} else {
-			return nil, fmt.Errorf("Failed to create directory: %s", err)
-		}
+		return nil, fmt.Errorf("Failed to create directory: %s", err)
 	}
 
 	return f, nil
 }
 
-func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args UploadSyncArgs, try int) error {
+func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args UploadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
+	err := self.retry(func(ctx context.Context) error {
+		return self.uploadMissingFileOnce(ctx, parentId, lf, args)
+	})
+	if isRetryableError(err) {
+		return fmt.Errorf("Failed to upload file: %s", err)
+	}
+	return err
+}
+
+func (self *Drive) uploadMissingFileOnce(ctx context.Context, parentId string, lf *LocalFile, args UploadSyncArgs) error {
 	srcFile, err := os.Open(lf.absPath)
 	if err != nil {
 		return fmt.Errorf("Failed to open file: %s", err)
@@ -310,15 +385,13 @@ func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args Upload
 	progressReader := getProgressReader(srcFile, args.Progress, lf.info.Size())
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(ctx, progressReader, args.Timeout)
 
 	_, err = self.service.Files.Create(dstFile).Fields("id", "name", "size", "md5Checksum").Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.uploadMissingFile(parentId, lf, args, try)
-		} else if isTimeoutError(err) {
+		if isRetryableError(err) {
+			return err
+		} else if self.isTimeoutError(err) {
 			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		} else {
 			return fmt.Errorf("Failed to upload file: %s", err)
@@ -328,11 +401,21 @@ func (self *Drive) uploadMissingFile(parentId string, lf *LocalFile, args Upload
 	return nil
 }
 
-func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try int) error {
+func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
+	err := self.retry(func(ctx context.Context) error {
+		return self.updateChangedFileOnce(ctx, cf, args)
+	})
+	if isRetryableError(err) {
+		return fmt.Errorf("Failed to update file: %s", err)
+	}
+	return err
+}
+
+func (self *Drive) updateChangedFileOnce(ctx context.Context, cf *changedFile, args UploadSyncArgs) error {
 	srcFile, err := os.Open(cf.local.absPath)
 	if err != nil {
 		return fmt.Errorf("Failed to open file: %s", err)
@@ -351,15 +434,13 @@ func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try i
 	progressReader := getProgressReader(srcFile, args.Progress, cf.local.info.Size())
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(ctx, progressReader, args.Timeout)
 
 	_, err = self.service.Files.Update(cf.remote.file.Id, dstFile).Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.updateChangedFile(cf, args, try)
-		} else if isTimeoutError(err) {
+		if isRetryableError(err) {
+			return err
+		} else if self.isTimeoutError(err) {
 			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		} else {
 			return fmt.Errorf("Failed to update file: %s", err)
@@ -369,20 +450,14 @@ func (self *Drive) updateChangedFile(cf *changedFile, args UploadSyncArgs, try i
 	return nil
 }
 
-func (self *Drive) deleteRemoteFile(rf *RemoteFile, args UploadSyncArgs, try int) error {
+func (self *Drive) deleteRemoteFile(rf *RemoteFile, args UploadSyncArgs) error {
 	if args.DryRun {
 		return nil
 	}
 
 	err := self.service.Files.Delete(rf.file.Id).Do()
 	if err != nil {
-		if isBackendOrRateLimitError(err) && try < MaxErrorRetries {
-			exponentialBackoffSleep(try)
-			try++
-			return self.deleteRemoteFile(rf, args, try)
-		} else {
-			return fmt.Errorf("Failed to delete file: %s", err)
-		}
+		return fmt.Errorf("Failed to delete file: %s", err)
 	}
 
 	return nil
@@ -398,45 +473,59 @@ func (self *Drive) dirIsEmpty(id string) (bool, error) {
 	return len(fileList.Files) == 0, nil
 }
 
-func checkRemoteConflict(cf *changedFile, resolution ConflictResolution) (bool, string) {
+// Decides what to do with a changed local file, the returned reason
+// describes why the remote file is skipped or kept
+func checkRemoteConflict(cf *changedFile, resolution ConflictResolution, prompt conflictPrompter) (conflictAction, string) {
 	// No conflict unless remote file was last modified
 	if cf.compareModTime() != RemoteLastModified {
-		return false, ""
+		return overwriteFile, ""
 	}
 
-	// Don't skip if want to keep the local file
-	if resolution == KeepLocal {
-		return false, ""
-	}
+	switch resolution {
+	case KeepLocal:
+		// Don't skip if want to keep the local file
+		return overwriteFile, ""
 
-	// Skip if we want to keep the remote file
-	if resolution == KeepRemote {
-		return true, "conflicting file, keeping remote file"
-	}
+	case KeepRemote:
+		return skipFile, "conflicting file, keeping remote file"
+
+	case KeepNewest:
+		// The remote file is the newest one, since this is a conflict
+		return skipFile, "conflicting file, remote file is newest, keeping remote"
+
+	case KeepBoth:
+		return keepBothFiles, "conflicting file, renaming remote file"
+
+	case Interactive:
+		action := prompt(cf)
+		if action == skipFile {
+			return skipFile, "conflicting file, keeping remote file"
+		}
+		return action, "conflicting file, renaming remote file"
 
-	if resolution == KeepLargest {
+	case KeepLargest:
 		largest := cf.compareSize()
 
 		// Skip if the remote file is largest
 		if largest == RemoteLargestSize {
-			return true, "conflicting file, remote file is largest, keeping remote"
+			return skipFile, "conflicting file, remote file is largest, keeping remote"
 		}
 
 		// Don't skip if the local file is largest
 		if largest == LocalLargestSize {
-			return false, ""
+			return overwriteFile, ""
 		}
 
 		// Keep remote if both files have the same size
 		if largest == EqualSize {
-			return true, "conflicting file, file sizes are equal, keeping remote"
+			return skipFile, "conflicting file, file sizes are equal, keeping remote"
 		}
 	}
 
 	// The conditionals above should cover all cases,
 	// unless the programmer did something wrong,
 	// in which case we default to being non-destructive and skip the file
-	return true, "conflicting file, unhandled case"
+	return skipFile, "conflicting file, unhandled case"
 }
 
 func ensureNoRemoteModifications(files []*changedFile) error {
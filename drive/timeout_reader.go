@@ -11,8 +11,8 @@ const TimeoutTimerInterval = time.Second * 10
 
 type timeoutReaderWrapper func(io.Reader) io.Reader
 
-func getTimeoutReaderWrapperContext(timeout time.Duration) (timeoutReaderWrapper, context.Context) {
-	ctx, cancel := context.WithCancel(context.TODO())
+func getTimeoutReaderWrapperContext(parent context.Context, timeout time.Duration) (timeoutReaderWrapper, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
 	wrapper := func(r io.Reader) io.Reader {
 		// Return untouched reader if timeout is 0
 		if timeout == 0 {
@@ -24,8 +24,8 @@ func getTimeoutReaderWrapperContext(timeout time.Duration) (timeoutReaderWrapper
 	return wrapper, ctx
 }
 
-func getTimeoutReaderContext(r io.Reader, timeout time.Duration) (io.Reader, context.Context) {
-	ctx, cancel := context.WithCancel(context.TODO())
+func getTimeoutReaderContext(parent context.Context, r io.Reader, timeout time.Duration) (io.Reader, context.Context) {
+	ctx, cancel := context.WithCancel(parent)
 
 	// Return untouched reader if timeout is 0
 	if timeout == 0 {
@@ -0,0 +1,90 @@
+package drive
+
+import (
+	"fmt"
+	"path"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// A file found when walking a directory tree
+type treeFile struct {
+	file *drive.File
+	// Slash separated path starting with the name of the root
+	path string
+	// Nil for the given files
+	parent *treeFile
+}
+
+// Returns the given files and, for directories, all files below them. The
+// tree is listed one level at a time using batch requests. The fields are
+// the file fields to get, i.e. id,name,mimeType, id, name and mimeType
+// are always required
+func (self *Drive) fileTree(ids []string, fields string) ([]*treeFile, error) {
+	return self.walkFileTree(ids, fileTreeArgs{fields: fields})
+}
+
+type fileTreeArgs struct {
+	// File fields to get, see fileTree
+	fields string
+	// Only list files owned by the user, directories
+	// owned by others are skipped with their content
+	ownedByMe bool
+}
+
+// Like fileTree, with an owner filter
+func (self *Drive) walkFileTree(ids []string, args fileTreeArgs) ([]*treeFile, error) {
+	fields := args.fields
+	roots, errors := self.batchGetFiles(ids, googleapi.Field(fields))
+
+	var files []*treeFile
+	var dirs []*treeFile
+
+	for i, f := range roots {
+		if errors[i] != nil {
+			return nil, fmt.Errorf("Failed to get file: %s", errors[i])
+		}
+
+		tf := &treeFile{file: f, path: f.Name}
+		files = append(files, tf)
+		if isDir(f) {
+			dirs = append(dirs, tf)
+		}
+	}
+
+	for len(dirs) > 0 {
+		if self.interrupted() {
+			return nil, ErrInterrupted
+		}
+
+		var queries []string
+		for _, d := range dirs {
+			query := childrenQuery(d.file.Id)
+			if args.ownedByMe {
+				query += " and 'me' in owners"
+			}
+			queries = append(queries, query)
+		}
+
+		children, err := self.batchListFiles(queries, "nextPageToken", googleapi.Field(fmt.Sprintf("files(%s)", fields)))
+		if err != nil {
+			return nil, fmt.Errorf("Failed listing files: %s", err)
+		}
+
+		parents := dirs
+		dirs = nil
+
+		for i, list := range children {
+			for _, f := range list {
+				tf := &treeFile{file: f, path: path.Join(parents[i].path, f.Name), parent: parents[i]}
+				files = append(files, tf)
+				if isDir(f) {
+					dirs = append(dirs, tf)
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
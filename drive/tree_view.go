@@ -0,0 +1,152 @@
+package drive
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+)
+
+type TreeArgs struct {
+	Out           io.Writer
+	Id            string
+	Depth         int64
+	DirsOnly      bool
+	IncludeShared bool
+	ShowDoc       bool
+	SizeInBytes   bool
+}
+
+func (args *TreeArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	return nil
+}
+
+type treeNode struct {
+	file     *drive.File
+	children []*treeNode
+	// Total size of all files below a directory
+	size int64
+}
+
+// Prints the directory tree with box drawing characters. The directory
+// sizes are the totals of all files below them, so the whole tree is
+// listed also when only the top levels are printed
+func (self *Drive) Tree(args TreeArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	files, err := self.walkFileTree([]string{args.Id}, fileTreeArgs{
+		fields:    "id,name,md5Checksum,mimeType,size",
+		ownedByMe: !args.IncludeShared,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !isDir(files[0].file) {
+		return fmt.Errorf("'%s' is not a directory", files[0].file.Name)
+	}
+
+	nodes := map[*treeFile]*treeNode{}
+	for _, tf := range files {
+		n := &treeNode{file: tf.file}
+		nodes[tf] = n
+		if tf.parent != nil {
+			parent := nodes[tf.parent]
+			parent.children = append(parent.children, n)
+		}
+	}
+
+	root := nodes[files[0]]
+	root.sort()
+	root.sum()
+
+	fmt.Fprintf(args.Out, "%s/ (%s)\n", root.file.Name, formatSize(root.size, args.SizeInBytes))
+	printTreeNodes(args, root.children, "", 1)
+	return nil
+}
+
+// Sorts the children by name, directories first
+func (self *treeNode) sort() {
+	sort.Sort(byTreeNodeName(self.children))
+	for _, child := range self.children {
+		child.sort()
+	}
+}
+
+// Sets the size of the directories to the size of their content
+func (self *treeNode) sum() int64 {
+	if !isDir(self.file) {
+		self.size = self.file.Size
+		return self.size
+	}
+
+	for _, child := range self.children {
+		self.size += child.sum()
+	}
+	return self.size
+}
+
+// Prints the nodes at the given depth and their children down to the depth limit
+func printTreeNodes(args TreeArgs, nodes []*treeNode, prefix string, depth int64) {
+	if args.Depth > 0 && depth > args.Depth {
+		return
+	}
+
+	var visible []*treeNode
+	for _, n := range nodes {
+		if args.DirsOnly && !isDir(n.file) {
+			continue
+		}
+		if isDoc(n.file) && !args.ShowDoc {
+			continue
+		}
+		visible = append(visible, n)
+	}
+
+	for i, n := range visible {
+		branch, indent := "├── ", "│   "
+		if i == len(visible)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		switch {
+		case isDir(n.file):
+			fmt.Fprintf(args.Out, "%s%s%s/ (%s)\n", prefix, branch, n.file.Name, formatSize(n.size, args.SizeInBytes))
+		case isShortcut(n.file):
+			fmt.Fprintf(args.Out, "%s%s%s (shortcut)\n", prefix, branch, n.file.Name)
+		case isDoc(n.file):
+			fmt.Fprintf(args.Out, "%s%s%s\n", prefix, branch, n.file.Name)
+		default:
+			fmt.Fprintf(args.Out, "%s%s%s (%s)\n", prefix, branch, n.file.Name, formatSize(n.file.Size, args.SizeInBytes))
+		}
+
+		printTreeNodes(args, n.children, prefix+indent, depth+1)
+	}
+}
+
+type byTreeNodeName []*treeNode
+
+func (self byTreeNodeName) Len() int {
+	return len(self)
+}
+
+func (self byTreeNodeName) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+}
+
+func (self byTreeNodeName) Less(i, j int) bool {
+	if isDir(self[i].file) != isDir(self[j].file) {
+		return isDir(self[i].file)
+	}
+	return strings.ToLower(self[i].file.Name) < strings.ToLower(self[j].file.Name)
+}
@@ -2,6 +2,7 @@ package drive
 
 import (
 	"fmt"
+	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"io"
@@ -38,10 +39,66 @@ type UpdateStreamArgs struct {
 	Timeout     time.Duration
 }
 
+func (args *UpdateArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	parents, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	args.Parents = parents
+	return nil
+}
+
+func (args *UpdateStreamArgs) normalize(drive *Drive) error {
+	id, err := drive.resolveFileId(args.Id)
+	if err != nil {
+		return err
+	}
+
+	parents, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
+	}
+
+	args.Id = id
+	args.Parents = parents
+	return nil
+}
+
 func (self *Drive) Update(args UpdateArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
+	var f *drive.File
+	var rate int64
+
+	err := self.retry(func(ctx context.Context) error {
+		var err error
+		f, rate, err = self.updateFile(ctx, args)
+		return err
+	})
+	if err != nil {
+		if isRetryableError(err) {
+			return fmt.Errorf("Failed to upload file: %s", err)
+		}
+		return err
+	}
+
+	fmt.Fprintf(args.Out, "Updated %s at %s/s, total %s\n", f.Id, formatSize(rate, false), formatSize(f.Size, false))
+	return nil
+}
+
+func (self *Drive) updateFile(ctx context.Context, args UpdateArgs) (*drive.File, int64, error) {
 	srcFile, srcFileInfo, err := openFile(args.Path)
 	if err != nil {
-		return fmt.Errorf("Failed to open file: %s", err)
+		return nil, 0, fmt.Errorf("Failed to open file: %s", err)
 	}
 
 	defer srcFile.Close()
@@ -73,27 +130,30 @@ func (self *Drive) Update(args UpdateArgs) error {
 	progressReader := getProgressReader(srcFile, args.Progress, srcFileInfo.Size())
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(ctx, progressReader, args.Timeout)
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", args.Path)
 	started := time.Now()
 
 	f, err := self.service.Files.Update(args.Id, dstFile).Fields("id", "name", "size").Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isTimeoutError(err) {
-			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
+		if isRetryableError(err) {
+			return nil, 0, err
+		} else if self.isTimeoutError(err) {
+			return nil, 0, fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		}
-		return fmt.Errorf("Failed to upload file: %s", err)
+		return nil, 0, fmt.Errorf("Failed to upload file: %s", err)
 	}
 
 	// Calculate average upload rate
-	rate := calcRate(f.Size, started, time.Now())
-
-	fmt.Fprintf(args.Out, "Updated %s at %s/s, total %s\n", f.Id, formatSize(rate, false), formatSize(f.Size, false))
-	return nil
+	return f, calcRate(f.Size, started, time.Now()), nil
 }
 
 func (self *Drive) UpdateStream(args UpdateStreamArgs) error {
+	if err := args.normalize(self); err != nil {
+		return err
+	}
+
 	if args.In == nil {
 		return fmt.Errorf("no input stream supplied")
 	}
@@ -125,14 +185,14 @@ func (self *Drive) UpdateStream(args UpdateStreamArgs) error {
 	progressReader := getProgressReader(args.In, args.Progress, 0)
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(self.ctx, progressReader, args.Timeout)
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", args.Name)
 	started := time.Now()
 
 	f, err := self.service.Files.Update(args.Id, dstFile).Fields("id", "name", "size").Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isTimeoutError(err) {
+		if self.isTimeoutError(err) {
 			return fmt.Errorf("failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		}
 		return fmt.Errorf("failed to upload file: %s", err)
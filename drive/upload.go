@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 )
@@ -25,21 +26,23 @@ type UploadArgs struct {
 	Delete      bool
 	ChunkSize   int64
 	Timeout     time.Duration
+	OcrLanguage string
 }
 
-func (args *UploadArgs) normalize(drive *Drive) {
-	var ids []string
-	finder := drive.newPathFinder()
-	for _, parent := range args.Parents {
-		id := finder.SecureFileId(parent)
-		ids = append(ids, id)
+func (args *UploadArgs) normalize(drive *Drive) error {
+	ids, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
 	}
 
 	args.Parents = ids
+	return nil
 }
 
 func (self *Drive) Upload(args UploadArgs) error {
-	args.normalize(self)
+	if err := args.normalize(self); err != nil {
+		return err
+	}
 
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
@@ -141,6 +144,10 @@ func (self *Drive) uploadDirectory(args UploadArgs) error {
 	}
 
 	for _, name := range names {
+		if self.interrupted() {
+			return ErrInterrupted
+		}
+
 		// Copy args and set new path and parents
 		newArgs := args
 		newArgs.Path = filepath.Join(args.Path, name)
@@ -158,6 +165,22 @@ func (self *Drive) uploadDirectory(args UploadArgs) error {
 }
 
 func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
+	var f *drive.File
+	var rate int64
+
+	err := self.retry(func(ctx context.Context) error {
+		var err error
+		f, rate, err = self.uploadFileOnce(ctx, args)
+		return err
+	})
+	if isRetryableError(err) {
+		return nil, 0, fmt.Errorf("Failed to upload file: %s", err)
+	}
+
+	return f, rate, err
+}
+
+func (self *Drive) uploadFileOnce(ctx context.Context, args UploadArgs) (*drive.File, int64, error) {
 	srcFile, srcFileInfo, err := openFile(args.Path)
 	if err != nil {
 		return nil, 0, err
@@ -193,14 +216,23 @@ func (self *Drive) uploadFile(args UploadArgs) (*drive.File, int64, error) {
 	progressReader := getProgressReader(srcFile, args.Progress, srcFileInfo.Size())
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(ctx, progressReader, args.Timeout)
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", args.Path)
 	started := time.Now()
 
-	f, err := self.service.Files.Create(dstFile).Fields("id", "name", "size", "md5Checksum", "webContentLink").Context(ctx).Media(reader, chunkSize).Do()
+	createCall := self.service.Files.Create(dstFile).Fields("id", "name", "size", "md5Checksum", "webContentLink")
+
+	// Language hint for ocr when importing images and pdfs
+	if args.OcrLanguage != "" {
+		createCall = createCall.OcrLanguage(args.OcrLanguage)
+	}
+
+	f, err := createCall.Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isTimeoutError(err) {
+		if isRetryableError(err) {
+			return nil, 0, err
+		} else if self.isTimeoutError(err) {
 			return nil, 0, fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		}
 		return nil, 0, fmt.Errorf("Failed to upload file: %s", err)
@@ -225,19 +257,20 @@ type UploadStreamArgs struct {
 	Timeout     time.Duration
 }
 
-func (args *UploadStreamArgs) normalize(drive *Drive) {
-	var ids []string
-	finder := drive.newPathFinder()
-	for _, parent := range args.Parents {
-		id := finder.SecureFileId(parent)
-		ids = append(ids, id)
+func (args *UploadStreamArgs) normalize(drive *Drive) error {
+	ids, err := drive.resolveFileIdList(args.Parents)
+	if err != nil {
+		return err
 	}
 
 	args.Parents = ids
+	return nil
 }
 
 func (self *Drive) UploadStream(args UploadStreamArgs) error {
-	args.normalize(self)
+	if err := args.normalize(self); err != nil {
+		return err
+	}
 
 	if args.ChunkSize > intMax()-1 {
 		return fmt.Errorf("Chunk size is to big, max chunk size for this computer is %d", intMax()-1)
@@ -261,14 +294,14 @@ func (self *Drive) UploadStream(args UploadStreamArgs) error {
 	progressReader := getProgressReader(args.In, args.Progress, 0)
 
 	// Wrap reader in timeout reader
-	reader, ctx := getTimeoutReaderContext(progressReader, args.Timeout)
+	reader, ctx := getTimeoutReaderContext(self.ctx, progressReader, args.Timeout)
 
 	fmt.Fprintf(args.Out, "Uploading %s\n", dstFile.Name)
 	started := time.Now()
 
 	f, err := self.service.Files.Create(dstFile).Fields("id", "name", "size", "webContentLink").Context(ctx).Media(reader, chunkSize).Do()
 	if err != nil {
-		if isTimeoutError(err) {
+		if self.isTimeoutError(err) {
 			return fmt.Errorf("Failed to upload file: timeout, no data was transferred for %v", args.Timeout)
 		}
 		return fmt.Errorf("Failed to upload file: %s", err)
@@ -21,6 +21,14 @@ const DefaultTimeout = 5 * 60
 const DefaultQuery = "trashed = false and 'me' in owners"
 const DefaultShareRole = "reader"
 const DefaultShareType = "anyone"
+const DefaultMaxRetries = 5
+const DefaultDiffContext = 3
+const DefaultAuditFormat = "csv"
+const DefaultDuDepth = 1
+const DefaultDedupeMode = "list"
+const DefaultSearchFormat = "table"
+const DefaultCommentsFormat = "table"
+const ExitCodeInterrupted = 130
 
 var DefaultConfigDir = GetDefaultConfigDir()
 
@@ -47,6 +55,80 @@ func main() {
 			Patterns:    []string{"--service-account"},
 			Description: "Oauth service account filename, used for server to server communication without user interaction (filename path is relative to config dir)",
 		},
+		cli.IntFlag{
+			Name:         "maxRetries",
+			Patterns:     []string{"--max-retries"},
+			Description:  fmt.Sprintf("Max number of times a request is retried on backend and rate limit errors, default: %d", DefaultMaxRetries),
+			DefaultValue: DefaultMaxRetries,
+		},
+		cli.BoolFlag{
+			Name:        "noCache",
+			Patterns:    []string{"--no-cache"},
+			Description: "Do not use the metadata cache for path resolution",
+			OmitValue:   true,
+		},
+		cli.StringFlag{
+			Name:        "cachePath",
+			Patterns:    []string{"--cache-path"},
+			Description: fmt.Sprintf("Path of the metadata cache, default: %s in the config dir", DefaultMetadataCacheFileName),
+		},
+	}
+
+	queryFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:        "nameContains",
+			Patterns:    []string{"--name-contains"},
+			Description: "Only files with names containing the given text",
+		},
+		cli.StringFlag{
+			Name:        "mime",
+			Patterns:    []string{"--mime"},
+			Description: "Only files with the given mime type",
+		},
+		cli.StringFlag{
+			Name:        "in",
+			Patterns:    []string{"--in"},
+			Description: "Only files in the given directory, path or id",
+		},
+		cli.StringFlag{
+			Name:        "modifiedAfter",
+			Patterns:    []string{"--modified-after"},
+			Description: "Only files modified after timestamp or age, i.e. 2026-10-10 or 7d",
+		},
+		cli.StringFlag{
+			Name:        "owner",
+			Patterns:    []string{"--owner"},
+			Description: "Only files owned by the given email address, use 'me' for your own files",
+		},
+		cli.BoolFlag{
+			Name:        "sharedWithMe",
+			Patterns:    []string{"--shared-with-me"},
+			Description: "Only files shared with you",
+			OmitValue:   true,
+		},
+		cli.BoolFlag{
+			Name:        "starred",
+			Patterns:    []string{"--starred"},
+			Description: "Only starred files",
+			OmitValue:   true,
+		},
+		cli.BoolFlag{
+			Name:        "trashed",
+			Patterns:    []string{"--trashed"},
+			Description: "Only trashed files",
+			OmitValue:   true,
+		},
+		cli.StringSliceFlag{
+			Name:        "appProperties",
+			Patterns:    []string{"--app-property"},
+			Description: "Only files with the given app property, key=value, can be specified multiple times",
+		},
+		cli.BoolFlag{
+			Name:        "showQuery",
+			Patterns:    []string{"--show-query"},
+			Description: "Print the generated query instead of running it",
+			OmitValue:   true,
+		},
 	}
 
 	handlers := []*cli.Handler{
@@ -56,7 +138,7 @@ func main() {
 			Callback:    listHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
-				cli.NewFlagGroup("options",
+				cli.NewFlagGroup("options", append([]cli.Flag{
 					cli.IntFlag{
 						Name:         "maxFiles",
 						Patterns:     []string{"-m", "--max"},
@@ -66,7 +148,7 @@ func main() {
 					cli.StringFlag{
 						Name:         "query",
 						Patterns:     []string{"-q", "--query"},
-						Description:  fmt.Sprintf(`Default query: "%s". See https://developers.google.com/drive/search-parameters`, DefaultQuery),
+						Description:  fmt.Sprintf(`Default query: "%s", the default is not used when filters are given. See https://developers.google.com/drive/search-parameters`, DefaultQuery),
 						DefaultValue: DefaultQuery,
 					},
 					cli.StringFlag{
@@ -98,6 +180,91 @@ func main() {
 						Description: "Size in bytes",
 						OmitValue:   true,
 					},
+				}, queryFlags...)...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] search [options] <terms>",
+			Description: "Search file content and metadata",
+			Callback:    searchHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "in",
+						Patterns:    []string{"--in"},
+						Description: "Only search in the given directory, path or id",
+					},
+					cli.StringFlag{
+						Name:        "mime",
+						Patterns:    []string{"--mime"},
+						Description: "Only search files with the given mime type",
+					},
+					cli.IntFlag{
+						Name:         "maxFiles",
+						Patterns:     []string{"-m", "--max"},
+						Description:  fmt.Sprintf("Max results, default: %d", DefaultMaxFiles),
+						DefaultValue: DefaultMaxFiles,
+					},
+					cli.StringFlag{
+						Name:         "format",
+						Patterns:     []string{"--format"},
+						Description:  fmt.Sprintf("Output format: table/json, default: %s", DefaultSearchFormat),
+						DefaultValue: DefaultSearchFormat,
+					},
+					cli.IntFlag{
+						Name:         "snippetWidth",
+						Patterns:     []string{"--snippet-width"},
+						Description:  fmt.Sprintf("Width of snippet column, default: %d, minimum: 9, use 0 for full width", DefaultNameWidth),
+						DefaultValue: DefaultNameWidth,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "download",
+						Patterns:    []string{"--download"},
+						Description: "Download the results",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "recursive",
+						Patterns:    []string{"-r", "--recursive"},
+						Description: "Download directories recursively, documents will be skipped",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "path",
+						Patterns:    []string{"--path"},
+						Description: "Download path",
+					},
+					cli.BoolFlag{
+						Name:        "force",
+						Patterns:    []string{"-f", "--force"},
+						Description: "Overwrite existing file",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "skip",
+						Patterns:    []string{"-s", "--skip"},
+						Description: "Skip existing files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
 				),
 			},
 		},
@@ -120,6 +287,77 @@ func main() {
 						Description: "List Google documents",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "includeShared",
+						Patterns:    []string{"--include-shared"},
+						Description: "Include files owned by others",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:        "depth",
+						Patterns:    []string{"--depth"},
+						Description: "Max depth of recursive listing, default: unlimited",
+					},
+					cli.BoolFlag{
+						Name:        "followShortcuts",
+						Patterns:    []string{"--follow-shortcuts"},
+						Description: "List content of directories that shortcuts point to when listing recursively",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "type",
+						Patterns:    []string{"--type"},
+						Description: "Only list files of type: file, dir, doc, shortcut or a mime type",
+					},
+					cli.StringFlag{
+						Name:        "nameGlob",
+						Patterns:    []string{"--name-glob"},
+						Description: "Only list files with names matching glob pattern, i.e. '*.pdf'",
+					},
+					cli.StringFlag{
+						Name:        "modifiedAfter",
+						Patterns:    []string{"--modified-after"},
+						Description: "Only list files modified after timestamp, i.e. 2026-10-10",
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] tree [options] <fileId>",
+			Description: "Print directory tree with sizes",
+			Callback:    treeHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:        "depth",
+						Patterns:    []string{"--depth"},
+						Description: "Max depth to print, directory sizes include the levels below, default: unlimited",
+					},
+					cli.BoolFlag{
+						Name:        "dirsOnly",
+						Patterns:    []string{"--dirs-only"},
+						Description: "Only print directories",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "includeShared",
+						Patterns:    []string{"--include-shared"},
+						Description: "Include files owned by others",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "doc",
+						Patterns:    []string{"-d", "--document"},
+						Description: "Print Google documents",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -186,7 +424,7 @@ func main() {
 			Callback:    downloadQueryHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
-				cli.NewFlagGroup("options",
+				cli.NewFlagGroup("options", append([]cli.Flag{
 					cli.BoolFlag{
 						Name:        "force",
 						Patterns:    []string{"-f", "--force"},
@@ -216,7 +454,7 @@ func main() {
 						Description: "Hide progress",
 						OmitValue:   true,
 					},
-				),
+				}, queryFlags...)...),
 			},
 		},
 		&cli.Handler{
@@ -446,6 +684,167 @@ func main() {
 				),
 			},
 		},
+		&cli.Handler{
+			Pattern:     "[global] comments list [options] <fileId>",
+			Description: "List comments and replies",
+			Callback:    listCommentsHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "unresolved",
+						Patterns:    []string{"--unresolved"},
+						Description: "Only list unresolved comments",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "includeDeleted",
+						Patterns:    []string{"--include-deleted"},
+						Description: "Include deleted comments and replies",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:         "format",
+						Patterns:     []string{"--format"},
+						Description:  fmt.Sprintf("Output format: table/json, default: %s", DefaultCommentsFormat),
+						DefaultValue: DefaultCommentsFormat,
+					},
+					cli.IntFlag{
+						Name:         "contentWidth",
+						Patterns:     []string{"--content-width"},
+						Description:  fmt.Sprintf("Width of content columns, default: %d, minimum: 9, use 0 for full width", DefaultNameWidth),
+						DefaultValue: DefaultNameWidth,
+					},
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] comments add [options] <fileId> <content>",
+			Description: "Add comment",
+			Callback:    addCommentHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "quote",
+						Patterns:    []string{"--quote"},
+						Description: "Text of the document the comment refers to",
+					},
+					cli.StringFlag{
+						Name:        "anchor",
+						Patterns:    []string{"--anchor"},
+						Description: "Region of the document the comment refers to, as json. See https://developers.google.com/drive/api/v3/manage-comments",
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] comments reply [options] <fileId> <commentId> <content>",
+			Description: "Reply to comment",
+			Callback:    replyCommentHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "resolve",
+						Patterns:    []string{"--resolve"},
+						Description: "Resolve the comment",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] comments resolve [options] <fileId> <commentId>",
+			Description: "Resolve comment",
+			Callback:    resolveCommentHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "message",
+						Patterns:    []string{"--message"},
+						Description: "Reply with message when resolving",
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] meta get <fileId>",
+			Description: "Get file metadata, including properties and app properties",
+			Callback:    getMetaHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] meta set [options] <fileId>",
+			Description: "Update file metadata without uploading content",
+			Callback:    setMetaHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "name",
+						Patterns:    []string{"--name"},
+						Description: "New file name",
+					},
+					cli.StringFlag{
+						Name:        "description",
+						Patterns:    []string{"--description"},
+						Description: "New file description, an empty value clears it",
+					},
+					cli.StringFlag{
+						Name:        "mime",
+						Patterns:    []string{"--mime"},
+						Description: "New mime type",
+					},
+					cli.BoolFlag{
+						Name:        "star",
+						Patterns:    []string{"--star"},
+						Description: "Star file",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "unstar",
+						Patterns:    []string{"--unstar"},
+						Description: "Unstar file",
+						OmitValue:   true,
+					},
+					cli.StringSliceFlag{
+						Name:        "properties",
+						Patterns:    []string{"--property"},
+						Description: "Property to set as key=value, use key= to remove a property, can be specified multiple times",
+					},
+					cli.StringSliceFlag{
+						Name:        "appProperties",
+						Patterns:    []string{"--app-property"},
+						Description: "App property to set as key=value, use key= to remove an app property, can be specified multiple times",
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] shortcut create [options] <targetId> <parentId>",
+			Description: "Create shortcut to file or directory",
+			Callback:    createShortcutHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "name",
+						Patterns:    []string{"--name"},
+						Description: "Shortcut name, defaults to the name of the target",
+					},
+				),
+			},
+		},
 		&cli.Handler{
 			Pattern:     "[global] mkdir [options] <name>",
 			Description: "Create directory",
@@ -504,28 +903,198 @@ func main() {
 					cli.BoolFlag{
 						Name:        "revoke",
 						Patterns:    []string{"--revoke"},
-						Description: "Delete all sharing permissions (owner roles will be skipped)",
+						Description: "Revoke the permissions matching type, email and domain instead of granting (owner roles will be skipped)",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "expires",
+						Patterns:    []string{"--expires"},
+						Description: "Expiration of the permission, a time like 2026-12-31T12:00Z or an age like 30d. Requires 'user' or 'group' as type",
+					},
+					cli.BoolFlag{
+						Name:        "notify",
+						Patterns:    []string{"--notify"},
+						Description: "Send notification emails to users and groups, not sent by default",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "message",
+						Patterns:    []string{"--message"},
+						Description: "Message included in the notification email, implies --notify",
+					},
+					cli.BoolFlag{
+						Name:        "transferOwnership",
+						Patterns:    []string{"--transfer-ownership"},
+						Description: "Make the user the owner of the file. Requires 'user' as type",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "recursive",
+						Patterns:    []string{"-r", "--recursive"},
+						Description: "Share or revoke on the directory and all it's content",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show what would have been shared or revoked",
 						OmitValue:   true,
 					},
 				),
 			},
 		},
 		&cli.Handler{
-			Pattern:     "[global] share list <fileId>",
-			Description: "List files permissions",
-			Callback:    shareListHandler,
+			Pattern:     "[global] share update [options] <fileId> <permissionId>",
+			Description: "Update permission",
+			Callback:    shareUpdateHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
-			},
-		},
-		&cli.Handler{
-			Pattern:     "[global] share revoke <fileId> <permissionId>",
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "role",
+						Patterns:    []string{"--role"},
+						Description: "Share role: writer/commenter/reader",
+					},
+					cli.StringFlag{
+						Name:        "expires",
+						Patterns:    []string{"--expires"},
+						Description: "Expiration of the permission, a time like 2026-12-31T12:00Z or an age like 30d",
+					},
+					cli.BoolFlag{
+						Name:        "transferOwnership",
+						Patterns:    []string{"--transfer-ownership"},
+						Description: "Make the user of the permission the owner of the file",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] share apply [options] <policyPath>",
+			Description: "Make folder permissions match a yaml or json policy file",
+			Callback:    shareApplyHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show the changes without applying them",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] share list <fileId>",
+			Description: "List files permissions",
+			Callback:    shareListHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] share revoke <fileId> <permissionId>",
 			Description: "Revoke permission",
 			Callback:    shareRevokeHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
 			},
 		},
+		&cli.Handler{
+			Pattern:     "[global] audit sharing [options] <fileId>",
+			Description: "Report permissions of all files in directory and flag risky grants",
+			Callback:    auditSharingHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "allowedDomains",
+						Patterns:    []string{"--external-domain-allowlist"},
+						Description: "Comma separated list of domains that are not external, grants to users, groups and domains outside them are flagged",
+					},
+					cli.StringFlag{
+						Name:         "format",
+						Patterns:     []string{"--format"},
+						Description:  fmt.Sprintf("Report format: csv/json, default: %s", DefaultAuditFormat),
+						DefaultValue: DefaultAuditFormat,
+					},
+					cli.BoolFlag{
+						Name:        "fix",
+						Patterns:    []string{"--fix"},
+						Description: "Revoke flagged permissions",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] du [options] <fileId>",
+			Description: "Show disk usage of directory and its subdirectories",
+			Callback:    diskUsageHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:         "depth",
+						Patterns:     []string{"--depth"},
+						Description:  fmt.Sprintf("Show subdirectories down to this depth, default: %d", DefaultDuDepth),
+						DefaultValue: DefaultDuDepth,
+					},
+					cli.IntFlag{
+						Name:        "top",
+						Patterns:    []string{"--top"},
+						Description: "Show the given number of largest files",
+					},
+					cli.BoolFlag{
+						Name:        "byOwner",
+						Patterns:    []string{"--by-owner"},
+						Description: "Show quota used per owner",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "byMimeType",
+						Patterns:    []string{"--by-mimetype"},
+						Description: "Show quota used per mime type",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] dedupe [options] <fileId>",
+			Description: "Find duplicate files in directory",
+			Callback:    dedupeHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:         "mode",
+						Patterns:     []string{"--mode"},
+						Description:  fmt.Sprintf("list: list files with the same content, trash: trash all but the newest copy, link: replace all but the newest copy with shortcuts, names: list files with the same name in the same directory, default: %s", DefaultDedupeMode),
+						DefaultValue: DefaultDedupeMode,
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show duplicates without changing anything",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
 		&cli.Handler{
 			Pattern:     "[global] delete [options] <fileId>",
 			Description: "Delete file or directory",
@@ -542,6 +1111,14 @@ func main() {
 				),
 			},
 		},
+		&cli.Handler{
+			Pattern:     "[global] move <fileId> <parentId>",
+			Description: "Move file or directory to another directory",
+			Callback:    moveHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
 		&cli.Handler{
 			Pattern:     "[global] sync list [options]",
 			Description: "List all syncable directories on drive",
@@ -616,18 +1193,58 @@ func main() {
 						Description: "Keep largest file when a conflict is encountered",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "keepNewest",
+						Patterns:    []string{"--keep-newest"},
+						Description: "Keep the most recently modified file when a conflict is encountered",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "keepBoth",
+						Patterns:    []string{"--keep-both"},
+						Description: "Keep both files when a conflict is encountered, the destination file is renamed",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "interactive",
+						Patterns:    []string{"-i", "--interactive"},
+						Description: "Ask what to do for each conflict",
+						OmitValue:   true,
+					},
 					cli.BoolFlag{
 						Name:        "deleteExtraneous",
 						Patterns:    []string{"--delete-extraneous"},
 						Description: "Delete extraneous local files",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "exportDocs",
+						Patterns:    []string{"--export-docs"},
+						Description: "Export google documents, spreadsheets and presentations",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "followShortcuts",
+						Patterns:    []string{"--follow-shortcuts"},
+						Description: "Download the files and directories that shortcuts point to",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "exportFormat",
+						Patterns:    []string{"--export-format"},
+						Description: "Comma separated list of export formats used with --export-docs, i.e. docx,xlsx,pptx. Documents without a matching format are exported with the default export mime",
+					},
 					cli.BoolFlag{
 						Name:        "dryRun",
 						Patterns:    []string{"--dry-run"},
 						Description: "Show what would have been transferred",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "plan",
+						Patterns:    []string{"--plan"},
+						Description: "Write the intended changes to the given file instead of syncing, see 'sync apply'",
+					},
 					cli.BoolFlag{
 						Name:        "noProgress",
 						Patterns:    []string{"--no-progress"},
@@ -668,6 +1285,24 @@ func main() {
 						Description: "Keep largest file when a conflict is encountered",
 						OmitValue:   true,
 					},
+					cli.BoolFlag{
+						Name:        "keepNewest",
+						Patterns:    []string{"--keep-newest"},
+						Description: "Keep the most recently modified file when a conflict is encountered",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "keepBoth",
+						Patterns:    []string{"--keep-both"},
+						Description: "Keep both files when a conflict is encountered, the destination file is renamed",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "interactive",
+						Patterns:    []string{"-i", "--interactive"},
+						Description: "Ask what to do for each conflict",
+						OmitValue:   true,
+					},
 					cli.BoolFlag{
 						Name:        "deleteExtraneous",
 						Patterns:    []string{"--delete-extraneous"},
@@ -680,6 +1315,39 @@ func main() {
 						Description: "Show what would have been transferred",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "plan",
+						Patterns:    []string{"--plan"},
+						Description: "Write the intended changes to the given file instead of syncing, see 'sync apply'",
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] sync apply [options] <planPath>",
+			Description: "Apply a sync plan created with --plan, the plan is only applied if no files have changed since it was created",
+			Callback:    applySyncHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
 					cli.BoolFlag{
 						Name:        "noProgress",
 						Patterns:    []string{"--no-progress"},
@@ -698,6 +1366,12 @@ func main() {
 						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
 						DefaultValue: DefaultUploadChunkSize,
 					},
+					cli.BoolFlag{
+						Name:        "skipConflicts",
+						Patterns:    []string{"--skip-conflicts"},
+						Description: "Apply a plan with conflicts, the conflicting files are left as is",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
@@ -816,6 +1490,262 @@ func main() {
 				cli.NewFlagGroup("global", globalFlags...),
 			},
 		},
+		&cli.Handler{
+			Pattern:     "[global] revision keep <fileId> <revId>",
+			Description: "Keep file revision forever",
+			Callback:    keepRevisionHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] revision unkeep <fileId> <revId>",
+			Description: "Stop keeping file revision forever",
+			Callback:    unkeepRevisionHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] revision restore [options] <fileId> <revId>",
+			Description: "Restore revision, the revision is uploaded as a new revision of the file",
+			Callback:    restoreRevisionHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] revision prune [options] <fileId>",
+			Description: "Delete old file revisions, revisions kept forever are not deleted",
+			Callback:    pruneRevisionsHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:        "keepLast",
+						Patterns:    []string{"--keep-last"},
+						Description: "Number of newest revisions to keep",
+					},
+					cli.StringFlag{
+						Name:        "olderThan",
+						Patterns:    []string{"--older-than"},
+						Description: "Only delete revisions older than this, i.e. 90d, 12h or 2w",
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show what would have been deleted",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] revision diff [options] <fileId> <revId> <otherRevId>",
+			Description: "Show the differences between two revisions of a text file",
+			Callback:    diffRevisionsHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:         "context",
+						Patterns:     []string{"--context"},
+						Description:  fmt.Sprintf("Number of context lines, default: %d", DefaultDiffContext),
+						DefaultValue: DefaultDiffContext,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] restore [options] <fileId>",
+			Description: "Restore directory as it was at the given time from file revisions and trash",
+			Callback:    restoreHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "asOf",
+						Patterns:    []string{"--as-of"},
+						Description: "Point in time to restore, i.e. 2026-10-10T12:00Z",
+					},
+					cli.StringFlag{
+						Name:        "path",
+						Patterns:    []string{"--path"},
+						Description: "Restore path",
+					},
+					cli.BoolFlag{
+						Name:        "force",
+						Patterns:    []string{"-f", "--force"},
+						Description: "Overwrite existing files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "remote",
+						Patterns:    []string{"--remote"},
+						Description: "Also upload the restored revisions as the new head revisions and restore trashed files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show what would have been restored",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Export format family for google documents: office, odf or pdf. Documents without a matching format are exported with the default export mime",
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] backup list [options] <fileId>",
+			Description: "List backup snapshots",
+			Callback:    listBackupsHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "skipHeader",
+						Patterns:    []string{"--no-header"},
+						Description: "Dont print the header",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Size in bytes",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] backup restore [options] <fileId> <snapshotId>",
+			Description: "Restore backup snapshot, use 'latest' to restore the newest snapshot",
+			Callback:    restoreBackupHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "path",
+						Patterns:    []string{"--path"},
+						Description: "Restore path",
+					},
+					cli.BoolFlag{
+						Name:        "force",
+						Patterns:    []string{"-f", "--force"},
+						Description: "Overwrite existing files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] backup prune [options] <fileId>",
+			Description: "Remove old backup snapshots and unused chunks",
+			Callback:    pruneBackupsHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.IntFlag{
+						Name:        "keepDaily",
+						Patterns:    []string{"--keep-daily"},
+						Description: "Number of days to keep the newest snapshot of",
+					},
+					cli.IntFlag{
+						Name:        "keepWeekly",
+						Patterns:    []string{"--keep-weekly"},
+						Description: "Number of weeks to keep the newest snapshot of",
+					},
+					cli.BoolFlag{
+						Name:        "dryRun",
+						Patterns:    []string{"--dry-run"},
+						Description: "Show what would have been removed",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] backup [options] <path> <fileId>",
+			Description: "Backup local directory to a drive directory, only new content is uploaded",
+			Callback:    backupHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+					cli.IntFlag{
+						Name:         "chunksize",
+						Patterns:     []string{"--chunksize"},
+						Description:  fmt.Sprintf("Set chunk size in bytes, default: %d", DefaultUploadChunkSize),
+						DefaultValue: DefaultUploadChunkSize,
+					},
+				),
+			},
+		},
 		&cli.Handler{
 			Pattern:     "[global] import [options] <path>",
 			Description: "Upload and convert file to a google document, see 'about import' for available conversions",
@@ -839,12 +1769,28 @@ func main() {
 						Patterns:    []string{"--mime"},
 						Description: "Mime type of imported file",
 					},
+					cli.StringFlag{
+						Name:        "to",
+						Patterns:    []string{"--to"},
+						Description: "Google document mime type to convert to, default is the first available conversion",
+					},
+					cli.StringFlag{
+						Name:        "ocrLanguage",
+						Patterns:    []string{"--ocr-language"},
+						Description: "Language hint for ocr processing when importing images and pdfs (ISO 639-1 code, i.e. en)",
+					},
+					cli.BoolFlag{
+						Name:        "recursive",
+						Patterns:    []string{"-r", "--recursive"},
+						Description: "Import directory recursively, files that can not be converted are skipped and reported",
+						OmitValue:   true,
+					},
 				),
 			},
 		},
 		&cli.Handler{
 			Pattern:     "[global] export [options] <fileId>",
-			Description: "Export a google document",
+			Description: "Export a google document or a directory of google documents",
 			Callback:    exportHandler,
 			FlagGroups: cli.FlagGroups{
 				cli.NewFlagGroup("global", globalFlags...),
@@ -866,6 +1812,72 @@ func main() {
 						Description: "Print available mime types for given file",
 						OmitValue:   true,
 					},
+					cli.StringFlag{
+						Name:        "format",
+						Patterns:    []string{"--format"},
+						Description: "Export format family: office, odf or pdf. Documents without a matching format are exported with the default export mime",
+					},
+					cli.BoolFlag{
+						Name:        "recursive",
+						Patterns:    []string{"-r", "--recursive"},
+						Description: "Export directory recursively, binary files are downloaded as is",
+						OmitValue:   true,
+					},
+					cli.StringFlag{
+						Name:        "path",
+						Patterns:    []string{"--path"},
+						Description: "Export path",
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] convert [options] <path>",
+			Description: "Convert a local file or directory to another format through google drive",
+			Callback:    convertHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.StringFlag{
+						Name:        "to",
+						Patterns:    []string{"--to"},
+						Description: "Format to convert to, i.e. pdf, docx or odt",
+					},
+					cli.StringFlag{
+						Name:        "output",
+						Patterns:    []string{"-o", "--output"},
+						Description: "Output file, or output directory when converting a directory. Defaults to the source path with the new extension",
+					},
+					cli.BoolFlag{
+						Name:        "force",
+						Patterns:    []string{"-f", "--force"},
+						Description: "Overwrite existing files",
+						OmitValue:   true,
+					},
+					cli.BoolFlag{
+						Name:        "noProgress",
+						Patterns:    []string{"--no-progress"},
+						Description: "Hide progress",
+						OmitValue:   true,
+					},
+					cli.IntFlag{
+						Name:         "timeout",
+						Patterns:     []string{"--timeout"},
+						Description:  fmt.Sprintf("Set timeout in seconds, use 0 for no timeout. Timeout is reached when no data is transferred in set amount of seconds, default: %d", DefaultTimeout),
+						DefaultValue: DefaultTimeout,
+					},
 				),
 			},
 		},
@@ -917,6 +1929,38 @@ func main() {
 				cli.NewFlagGroup("global", globalFlags...),
 			},
 		},
+		&cli.Handler{
+			Pattern:     "[global] cache status [options]",
+			Description: "Show metadata cache status",
+			Callback:    cacheStatusHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+				cli.NewFlagGroup("options",
+					cli.BoolFlag{
+						Name:        "sizeInBytes",
+						Patterns:    []string{"--bytes"},
+						Description: "Show size in bytes",
+						OmitValue:   true,
+					},
+				),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] cache refresh",
+			Description: "Apply remote changes to the metadata cache",
+			Callback:    cacheRefreshHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
+		&cli.Handler{
+			Pattern:     "[global] cache clear",
+			Description: "Remove the metadata cache",
+			Callback:    cacheClearHandler,
+			FlagGroups: cli.FlagGroups{
+				cli.NewFlagGroup("global", globalFlags...),
+			},
+		},
 		&cli.Handler{
 			Pattern:     "version",
 			Description: "Print application version",
@@ -940,6 +1984,7 @@ func main() {
 	}
 
 	cli.SetHandlers(handlers)
+	handleInterrupts()
 
 	if ok := cli.Handle(os.Args[1:]); !ok {
 		ExitF("No valid arguments given, use '%s help' to see available commands", Name)
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/BSIBusinessSoftware/gdrive/auth"
@@ -18,14 +20,26 @@ const ClientId = "367116221053-7n0vf5akeru7on6o2fjinrecpdoe99eg.apps.googleuserc
 const ClientSecret = "1qsNodXNaWq1mQuBjUjmvhoO"
 const TokenFilename = "token_v2.json"
 const DefaultCacheFileName = "file_cache.json"
+const DefaultExportCacheFileName = "export_cache.json"
+const DefaultMetadataCacheFileName = "metadata_cache.json"
 
 func listHandler(ctx cli.Context) {
 	args := ctx.Args()
+	filter := queryFilter(args)
+
+	// The default query would conflict with filters like --shared-with-me
+	query := args.String("query")
+	if query == DefaultQuery && !filter.IsEmpty() {
+		query = ""
+	}
+
 	err := newDrive(args).List(drive.ListFilesArgs{
 		Out:         os.Stdout,
 		MaxFiles:    args.Int64("maxFiles"),
 		NameWidth:   args.Int64("nameWidth"),
-		Query:       args.String("query"),
+		Query:       query,
+		Filter:      filter,
+		ShowQuery:   args.Bool("showQuery"),
 		SortOrder:   args.String("sortOrder"),
 		SkipHeader:  args.Bool("skipHeader"),
 		SizeInBytes: args.Bool("sizeInBytes"),
@@ -35,11 +49,61 @@ func listHandler(ctx cli.Context) {
 }
 func lsHandler(ctx cli.Context) {
 	args := ctx.Args()
+	var modifiedAfter time.Time
+	if value := args.String("modifiedAfter"); value != "" {
+		modifiedAfter = timestamp(value)
+	}
+
 	err := newDrive(args).ListDirectory(drive.ListDirectoryArgs{
-		Out:       os.Stdout,
-		Id:        args.String("fileId"),
-		Recursive: args.Bool("recursive"),
-		ShowDoc:   args.Bool("doc"),
+		Out:             os.Stdout,
+		Id:              args.String("fileId"),
+		Recursive:       args.Bool("recursive"),
+		ShowDoc:         args.Bool("doc"),
+		IncludeShared:   args.Bool("includeShared"),
+		Depth:           args.Int64("depth"),
+		FollowShortcuts: args.Bool("followShortcuts"),
+		Type:            args.String("type"),
+		NameGlob:        args.String("nameGlob"),
+		ModifiedAfter:   modifiedAfter,
+	})
+	checkErr(err)
+}
+
+func searchHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Search(drive.SearchArgs{
+		Out:      os.Stdout,
+		Status:   os.Stderr,
+		Progress: progressWriter(args.Bool("noProgress")),
+		Terms:    args.String("terms"),
+		Filter: drive.QueryFilter{
+			In:       args.String("in"),
+			MimeType: args.String("mime"),
+		},
+		MaxFiles:     args.Int64("maxFiles"),
+		Format:       args.String("format"),
+		SnippetWidth: args.Int64("snippetWidth"),
+		SkipHeader:   args.Bool("skipHeader"),
+		Download:     args.Bool("download"),
+		Recursive:    args.Bool("recursive"),
+		Path:         args.String("path"),
+		Force:        args.Bool("force"),
+		Skip:         args.Bool("skip"),
+		Timeout:      durationInSeconds(args.Int64("timeout")),
+	})
+	checkErr(err)
+}
+
+func treeHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Tree(drive.TreeArgs{
+		Out:           os.Stdout,
+		Id:            args.String("fileId"),
+		Depth:         args.Int64("depth"),
+		DirsOnly:      args.Bool("dirsOnly"),
+		IncludeShared: args.Bool("includeShared"),
+		ShowDoc:       args.Bool("doc"),
+		SizeInBytes:   args.Bool("sizeInBytes"),
 	})
 	checkErr(err)
 }
@@ -80,6 +144,8 @@ func downloadQueryHandler(ctx cli.Context) {
 	err := newDrive(args).DownloadQuery(drive.DownloadQueryArgs{
 		Out:       os.Stdout,
 		Query:     args.String("query"),
+		Filter:    queryFilter(args),
+		ShowQuery: args.Bool("showQuery"),
 		Force:     args.Bool("force"),
 		Skip:      args.Bool("skip"),
 		Recursive: args.Bool("recursive"),
@@ -92,6 +158,7 @@ func downloadQueryHandler(ctx cli.Context) {
 func downloadSyncHandler(ctx cli.Context) {
 	args := ctx.Args()
 	cachePath := filepath.Join(args.String("configDir"), DefaultCacheFileName)
+	exportCachePath := filepath.Join(args.String("configDir"), DefaultExportCacheFileName)
 	err := newDrive(args).DownloadSync(drive.DownloadSyncArgs{
 		Out:              os.Stdout,
 		Progress:         progressWriter(args.Bool("noProgress")),
@@ -101,7 +168,13 @@ func downloadSyncHandler(ctx cli.Context) {
 		DeleteExtraneous: args.Bool("deleteExtraneous"),
 		Timeout:          durationInSeconds(args.Int64("timeout")),
 		Resolution:       conflictResolution(args),
+		In:               os.Stdin,
 		Comparer:         NewCachedMd5Comparer(cachePath),
+		ExportDocs:       args.Bool("exportDocs"),
+		FollowShortcuts:  args.Bool("followShortcuts"),
+		ExportFormats:    splitList(args.String("exportFormat")),
+		ExportTracker:    NewCachedExportTracker(exportCachePath),
+		Plan:             args.String("plan"),
 	})
 	checkErr(err)
 }
@@ -171,7 +244,26 @@ func uploadSyncHandler(ctx cli.Context) {
 		ChunkSize:        args.Int64("chunksize"),
 		Timeout:          durationInSeconds(args.Int64("timeout")),
 		Resolution:       conflictResolution(args),
+		In:               os.Stdin,
 		Comparer:         NewCachedMd5Comparer(cachePath),
+		Plan:             args.String("plan"),
+	})
+	checkErr(err)
+}
+
+func applySyncHandler(ctx cli.Context) {
+	args := ctx.Args()
+	cachePath := filepath.Join(args.String("configDir"), DefaultCacheFileName)
+	exportCachePath := filepath.Join(args.String("configDir"), DefaultExportCacheFileName)
+	err := newDrive(args).ApplySyncPlan(drive.ApplySyncPlanArgs{
+		Out:           os.Stdout,
+		Progress:      progressWriter(args.Bool("noProgress")),
+		PlanPath:      args.String("planPath"),
+		ChunkSize:     args.Int64("chunksize"),
+		Timeout:       durationInSeconds(args.Int64("timeout")),
+		Comparer:      NewCachedMd5Comparer(cachePath),
+		ExportTracker: NewCachedExportTracker(exportCachePath),
+		SkipConflicts: args.Bool("skipConflicts"),
 	})
 	checkErr(err)
 }
@@ -223,11 +315,14 @@ func infoHandler(ctx cli.Context) {
 func importHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).Import(drive.ImportArgs{
-		Mime:     args.String("mime"),
-		Out:      os.Stdout,
-		Path:     args.String("path"),
-		Parents:  args.StringSlice("parent"),
-		Progress: progressWriter(args.Bool("noProgress")),
+		Mime:        args.String("mime"),
+		To:          args.String("to"),
+		OcrLanguage: args.String("ocrLanguage"),
+		Out:         os.Stdout,
+		Path:        args.String("path"),
+		Parents:     args.StringSlice("parent"),
+		Recursive:   args.Bool("recursive"),
+		Progress:    progressWriter(args.Bool("noProgress")),
 	})
 	checkErr(err)
 }
@@ -236,10 +331,29 @@ func exportHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).Export(drive.ExportArgs{
 		Out:        os.Stdout,
+		Progress:   progressWriter(args.Bool("noProgress")),
 		Id:         args.String("fileId"),
 		Mime:       args.String("mime"),
+		Format:     args.String("format"),
+		Path:       args.String("path"),
 		PrintMimes: args.Bool("printMimes"),
 		Force:      args.Bool("force"),
+		Recursive:  args.Bool("recursive"),
+		Timeout:    durationInSeconds(args.Int64("timeout")),
+	})
+	checkErr(err)
+}
+
+func convertHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Convert(drive.ConvertArgs{
+		Out:      os.Stdout,
+		Progress: progressWriter(args.Bool("noProgress")),
+		Path:     args.String("path"),
+		To:       args.String("to"),
+		Output:   args.String("output"),
+		Force:    args.Bool("force"),
+		Timeout:  durationInSeconds(args.Int64("timeout")),
 	})
 	checkErr(err)
 }
@@ -267,16 +381,139 @@ func mkdirHandler(ctx cli.Context) {
 	checkErr(err)
 }
 
+func listCommentsHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ListComments(drive.ListCommentsArgs{
+		Out:            os.Stdout,
+		FileId:         args.String("fileId"),
+		Unresolved:     args.Bool("unresolved"),
+		IncludeDeleted: args.Bool("includeDeleted"),
+		Format:         args.String("format"),
+		ContentWidth:   args.Int64("contentWidth"),
+		SkipHeader:     args.Bool("skipHeader"),
+	})
+	checkErr(err)
+}
+
+func addCommentHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).AddComment(drive.AddCommentArgs{
+		Out:     os.Stdout,
+		FileId:  args.String("fileId"),
+		Content: args.String("content"),
+		Quote:   args.String("quote"),
+		Anchor:  args.String("anchor"),
+	})
+	checkErr(err)
+}
+
+func replyCommentHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ReplyComment(drive.ReplyCommentArgs{
+		Out:       os.Stdout,
+		FileId:    args.String("fileId"),
+		CommentId: args.String("commentId"),
+		Content:   args.String("content"),
+		Resolve:   args.Bool("resolve"),
+	})
+	checkErr(err)
+}
+
+func resolveCommentHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ReplyComment(drive.ReplyCommentArgs{
+		Out:       os.Stdout,
+		FileId:    args.String("fileId"),
+		CommentId: args.String("commentId"),
+		Content:   args.String("message"),
+		Resolve:   true,
+	})
+	checkErr(err)
+}
+
+func getMetaHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).GetMeta(drive.GetMetaArgs{
+		Out: os.Stdout,
+		Id:  args.String("fileId"),
+	})
+	checkErr(err)
+}
+
+func setMetaHandler(ctx cli.Context) {
+	args := ctx.Args()
+
+	var description *string
+	if args.IsSet("description") {
+		value := args.String("description")
+		description = &value
+	}
+
+	err := newDrive(args).SetMeta(drive.SetMetaArgs{
+		Out:           os.Stdout,
+		Id:            args.String("fileId"),
+		Name:          args.String("name"),
+		Description:   description,
+		MimeType:      args.String("mime"),
+		Star:          args.Bool("star"),
+		Unstar:        args.Bool("unstar"),
+		Properties:    args.StringSlice("properties"),
+		AppProperties: args.StringSlice("appProperties"),
+	})
+	checkErr(err)
+}
+
+func createShortcutHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).CreateShortcut(drive.CreateShortcutArgs{
+		Out:      os.Stdout,
+		TargetId: args.String("targetId"),
+		ParentId: args.String("parentId"),
+		Name:     args.String("name"),
+	})
+	checkErr(err)
+}
+
 func shareHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).Share(drive.ShareArgs{
-		Out:          os.Stdout,
-		FileId:       args.String("fileId"),
-		Role:         args.String("role"),
-		Type:         args.String("type"),
-		Email:        args.String("email"),
-		Domain:       args.String("domain"),
-		Discoverable: args.Bool("discoverable"),
+		Out:               os.Stdout,
+		FileId:            args.String("fileId"),
+		Role:              args.String("role"),
+		Type:              args.String("type"),
+		Email:             args.String("email"),
+		Domain:            args.String("domain"),
+		Discoverable:      args.Bool("discoverable"),
+		Expires:           expiration(args.String("expires")),
+		Notify:            args.Bool("notify"),
+		Message:           args.String("message"),
+		TransferOwnership: args.Bool("transferOwnership"),
+		Recursive:         args.Bool("recursive"),
+		Revoke:            args.Bool("revoke"),
+		DryRun:            args.Bool("dryRun"),
+	})
+	checkErr(err)
+}
+
+func shareUpdateHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).UpdatePermission(drive.UpdatePermissionArgs{
+		Out:               os.Stdout,
+		FileId:            args.String("fileId"),
+		PermissionId:      args.String("permissionId"),
+		Role:              args.String("role"),
+		Expires:           expiration(args.String("expires")),
+		TransferOwnership: args.Bool("transferOwnership"),
+	})
+	checkErr(err)
+}
+
+func shareApplyHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ApplySharePolicy(drive.ApplySharePolicyArgs{
+		Out:        os.Stdout,
+		PolicyPath: args.String("policyPath"),
+		DryRun:     args.Bool("dryRun"),
 	})
 	checkErr(err)
 }
@@ -300,6 +537,45 @@ func shareRevokeHandler(ctx cli.Context) {
 	checkErr(err)
 }
 
+func auditSharingHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).AuditSharing(drive.AuditSharingArgs{
+		Out:            os.Stdout,
+		Status:         os.Stderr,
+		Id:             args.String("fileId"),
+		AllowedDomains: splitList(args.String("allowedDomains")),
+		Format:         args.String("format"),
+		Fix:            args.Bool("fix"),
+	})
+	checkErr(err)
+}
+
+func diskUsageHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).DiskUsage(drive.DiskUsageArgs{
+		Out:         os.Stdout,
+		Id:          args.String("fileId"),
+		Depth:       args.Int64("depth"),
+		Top:         args.Int64("top"),
+		ByOwner:     args.Bool("byOwner"),
+		ByMimeType:  args.Bool("byMimeType"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+	})
+	checkErr(err)
+}
+
+func dedupeHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Dedupe(drive.DedupeArgs{
+		Out:         os.Stdout,
+		Id:          args.String("fileId"),
+		Mode:        args.String("mode"),
+		DryRun:      args.Bool("dryRun"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+	})
+	checkErr(err)
+}
+
 func deleteHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).Delete(drive.DeleteArgs{
@@ -310,6 +586,16 @@ func deleteHandler(ctx cli.Context) {
 	checkErr(err)
 }
 
+func moveHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Move(drive.MoveArgs{
+		Out:      os.Stdout,
+		Id:       args.String("fileId"),
+		ParentId: args.String("parentId"),
+	})
+	checkErr(err)
+}
+
 func listSyncHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).ListSync(drive.ListSyncArgs{
@@ -342,6 +628,137 @@ func deleteRevisionHandler(ctx cli.Context) {
 	checkErr(err)
 }
 
+func keepRevisionHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).KeepRevision(drive.KeepRevisionArgs{
+		Out:        os.Stdout,
+		FileId:     args.String("fileId"),
+		RevisionId: args.String("revId"),
+		Keep:       true,
+	})
+	checkErr(err)
+}
+
+func unkeepRevisionHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).KeepRevision(drive.KeepRevisionArgs{
+		Out:        os.Stdout,
+		FileId:     args.String("fileId"),
+		RevisionId: args.String("revId"),
+		Keep:       false,
+	})
+	checkErr(err)
+}
+
+func restoreRevisionHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).RestoreRevision(drive.RestoreRevisionArgs{
+		Out:        os.Stdout,
+		Progress:   progressWriter(args.Bool("noProgress")),
+		FileId:     args.String("fileId"),
+		RevisionId: args.String("revId"),
+		ChunkSize:  args.Int64("chunksize"),
+		Timeout:    durationInSeconds(args.Int64("timeout")),
+	})
+	checkErr(err)
+}
+
+func pruneRevisionsHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).PruneRevisions(drive.PruneRevisionsArgs{
+		Out:         os.Stdout,
+		FileId:      args.String("fileId"),
+		KeepLast:    args.Int64("keepLast"),
+		OlderThan:   age(args.String("olderThan")),
+		DryRun:      args.Bool("dryRun"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+	})
+	checkErr(err)
+}
+
+func diffRevisionsHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).DiffRevisions(drive.DiffRevisionsArgs{
+		Out:             os.Stdout,
+		FileId:          args.String("fileId"),
+		RevisionId:      args.String("revId"),
+		OtherRevisionId: args.String("otherRevId"),
+		Context:         args.Int64("context"),
+	})
+	checkErr(err)
+}
+
+func restoreHandler(ctx cli.Context) {
+	args := ctx.Args()
+	if args.String("asOf") == "" {
+		ExitF("--as-of is required")
+	}
+
+	err := newDrive(args).Restore(drive.RestoreArgs{
+		Out:       os.Stdout,
+		Progress:  progressWriter(args.Bool("noProgress")),
+		Id:        args.String("fileId"),
+		AsOf:      timestamp(args.String("asOf")),
+		Path:      args.String("path"),
+		Force:     args.Bool("force"),
+		Remote:    args.Bool("remote"),
+		DryRun:    args.Bool("dryRun"),
+		Format:    args.String("format"),
+		ChunkSize: args.Int64("chunksize"),
+		Timeout:   durationInSeconds(args.Int64("timeout")),
+	})
+	checkErr(err)
+}
+
+func backupHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).Backup(drive.BackupArgs{
+		Out:       os.Stdout,
+		Progress:  progressWriter(args.Bool("noProgress")),
+		Path:      args.String("path"),
+		Id:        args.String("fileId"),
+		ChunkSize: args.Int64("chunksize"),
+		Timeout:   durationInSeconds(args.Int64("timeout")),
+	})
+	checkErr(err)
+}
+
+func listBackupsHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).ListBackups(drive.ListBackupsArgs{
+		Out:         os.Stdout,
+		Id:          args.String("fileId"),
+		SkipHeader:  args.Bool("skipHeader"),
+		SizeInBytes: args.Bool("sizeInBytes"),
+	})
+	checkErr(err)
+}
+
+func restoreBackupHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).RestoreBackup(drive.RestoreBackupArgs{
+		Out:        os.Stdout,
+		Progress:   progressWriter(args.Bool("noProgress")),
+		Id:         args.String("fileId"),
+		SnapshotId: args.String("snapshotId"),
+		Path:       args.String("path"),
+		Force:      args.Bool("force"),
+	})
+	checkErr(err)
+}
+
+func pruneBackupsHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).PruneBackups(drive.PruneBackupsArgs{
+		Out:        os.Stdout,
+		Id:         args.String("fileId"),
+		KeepDaily:  args.Int64("keepDaily"),
+		KeepWeekly: args.Int64("keepWeekly"),
+		DryRun:     args.Bool("dryRun"),
+	})
+	checkErr(err)
+}
+
 func aboutHandler(ctx cli.Context) {
 	args := ctx.Args()
 	err := newDrive(args).About(drive.AboutArgs{
@@ -377,6 +794,31 @@ func idHandler(ctx cli.Context) {
 	checkErr(err)
 }
 
+func cacheStatusHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).CacheStatus(drive.CacheStatusArgs{
+		Out:         os.Stdout,
+		SizeInBytes: args.Bool("sizeInBytes"),
+	})
+	checkErr(err)
+}
+
+func cacheRefreshHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).CacheRefresh(drive.CacheRefreshArgs{
+		Out: os.Stdout,
+	})
+	checkErr(err)
+}
+
+func cacheClearHandler(ctx cli.Context) {
+	args := ctx.Args()
+	err := newDrive(args).CacheClear(drive.CacheClearArgs{
+		Out: os.Stdout,
+	})
+	checkErr(err)
+}
+
 func getOauthClient(args cli.Arguments) (*http.Client, error) {
 
 	client := auth.NewAuthorizedClient(ClientId, ClientSecret)
@@ -416,6 +858,13 @@ func getConfigDir(args cli.Arguments) string {
 	return args.String("configDir")
 }
 
+func getMetadataCachePath(args cli.Arguments) string {
+	if path := args.String("cachePath"); path != "" {
+		return path
+	}
+	return ConfigFilePath(getConfigDir(args), DefaultMetadataCacheFileName)
+}
+
 func newDrive(args cli.Arguments) *drive.Drive {
 	oauth, err := getOauthClient(args)
 	if err != nil {
@@ -427,6 +876,12 @@ func newDrive(args cli.Arguments) *drive.Drive {
 		ExitF("Failed getting drive: %s", err.Error())
 	}
 
+	client.SetMaxRetries(int(args.Int64("maxRetries")))
+	client.SetContext(interruptContext)
+	if !args.Bool("noCache") {
+		client.UseMetadataCache(getMetadataCachePath(args))
+	}
+
 	return client
 }
 
@@ -456,28 +911,117 @@ func durationInSeconds(seconds int64) time.Duration {
 	return time.Second * time.Duration(seconds)
 }
 
-func conflictResolution(args cli.Arguments) drive.ConflictResolution {
-	keepLocal := args.Bool("keepLocal")
-	keepRemote := args.Bool("keepRemote")
-	keepLargest := args.Bool("keepLargest")
+// Parses an age like 90d, 2w or 12h, empty means no age
+func age(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
 
-	if (keepLocal && keepRemote) || (keepLocal && keepLargest) || (keepRemote && keepLargest) {
-		ExitF("Only one conflict resolution flag can be given")
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) {
+			return time.Duration(n) * unit
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		ExitF("Invalid age '%s', use i.e. 90d, 2w or 12h", value)
+	}
+	return d
+}
+
+// Parses a point in time, either a timestamp or an age like 7d
+func since(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	if strings.Contains(value, "-") {
+		return timestamp(value)
+	}
+	return time.Now().Add(-age(value))
+}
+
+func queryFilter(args cli.Arguments) drive.QueryFilter {
+	return drive.QueryFilter{
+		NameContains:  args.String("nameContains"),
+		MimeType:      args.String("mime"),
+		In:            args.String("in"),
+		ModifiedAfter: since(args.String("modifiedAfter")),
+		Owner:         args.String("owner"),
+		SharedWithMe:  args.Bool("sharedWithMe"),
+		Starred:       args.Bool("starred"),
+		Trashed:       args.Bool("trashed"),
+		AppProperties: args.StringSlice("appProperties"),
 	}
+}
 
-	if keepLocal {
-		return drive.KeepLocal
+// Parses an expiration, either a timestamp or an age from now like 30d
+func expiration(value string) time.Time {
+	if value == "" {
+		return time.Time{}
 	}
 
-	if keepRemote {
-		return drive.KeepRemote
+	// Ages never contain dashes, timestamps always do
+	if strings.Contains(value, "-") {
+		return timestamp(value)
 	}
+	return time.Now().Add(age(value))
+}
+
+// Parses a timestamp like 2026-10-10T12:00Z, times without
+// a time zone and plain dates are in local time
+func timestamp(value string) time.Time {
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02",
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+
+	ExitF("Invalid timestamp '%s', use i.e. 2026-10-10T12:00Z or 2026-10-10", value)
+	return time.Time{}
+}
 
-	if keepLargest {
-		return drive.KeepLargest
+func conflictResolution(args cli.Arguments) drive.ConflictResolution {
+	flags := []struct {
+		name       string
+		resolution drive.ConflictResolution
+	}{
+		{"keepLocal", drive.KeepLocal},
+		{"keepRemote", drive.KeepRemote},
+		{"keepLargest", drive.KeepLargest},
+		{"keepNewest", drive.KeepNewest},
+		{"keepBoth", drive.KeepBoth},
+		{"interactive", drive.Interactive},
+	}
+
+	resolution := drive.NoResolution
+	count := 0
+
+	for _, flag := range flags {
+		if args.Bool(flag.name) {
+			resolution = flag.resolution
+			count++
+		}
+	}
+
+	if count > 1 {
+		ExitF("Only one conflict resolution flag can be given")
 	}
 
-	return drive.NoResolution
+	return resolution
 }
 
 func checkUploadArgs(args cli.Arguments) {
@@ -6,8 +6,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/BSIBusinessSoftware/gdrive/drive"
+	"golang.org/x/net/context"
 )
 
 func GetDefaultConfigDir() string {
@@ -47,6 +53,18 @@ func equal(a, b []string) bool {
 	return true
 }
 
+// Splits a comma separated list, empty items are skipped
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func ExitF(format string, a ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, a...)
 	fmt.Println("")
@@ -54,10 +72,37 @@ func ExitF(format string, a ...interface{}) {
 }
 
 func checkErr(err error) {
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	if err == nil {
+		return
+	}
+
+	if interruptContext.Err() != nil {
+		fmt.Fprintln(os.Stderr, drive.ErrInterrupted)
+		os.Exit(ExitCodeInterrupted)
 	}
+
+	fmt.Println(err)
+	os.Exit(1)
+}
+
+// Context that is canceled on SIGINT or SIGTERM
+var interruptContext, cancelInterruptContext = context.WithCancel(context.Background())
+
+// Cancels the interrupt context on the first SIGINT or SIGTERM, which makes
+// the running command abort the current transfer and clean up after itself.
+// A second signal exits immediately
+func handleInterrupts() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "\nInterrupted, aborting... (press ctrl-c again to quit immediately)")
+		cancelInterruptContext()
+
+		<-signals
+		os.Exit(ExitCodeInterrupted)
+	}()
 }
 
 func writeJson(path string, data interface{}) error {